@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"strconv"
+	"syscall"
 
+	"my_backend/internal/broadcast"
+	"my_backend/internal/config"
 	"my_backend/internal/database"
+	"my_backend/internal/domain"
 	"my_backend/internal/handler"
+	"my_backend/internal/logging"
+	"my_backend/internal/metrics"
 	"my_backend/internal/repository"
 	"my_backend/internal/service"
+	"my_backend/internal/storage"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 )
 
@@ -20,103 +28,277 @@ func main() {
 	// Load .env file (ignore error if not found - production uses env vars)
 	_ = godotenv.Load()
 
-	// 1. Connect to Database
-	if err := database.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("Failed to load config", err)
 	}
-	defer database.Close()
 
-	// 2. Run Migrations
-	if err := database.RunMigrations(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	logger, err := logging.New(cfg.Log.Level)
+	if err != nil {
+		fatal("Failed to create logger", err)
 	}
+	slog.SetDefault(logger) // Also routes the standard log package through slog
+	logger.Info("Effective config", "config", cfg.String())
 
-	// 3. Init Dependencies
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		if os.Getenv("ENV") == "production" {
-			log.Fatal("JWT_SECRET environment variable is required in production")
+	registry := metrics.NewRegistry()
+	metrics.RegisterRuntime(registry)
+
+	// 1. Select storage: Postgres by default, or process memory for local
+	// development and tests (data is lost on restart)
+	var (
+		pool         *pgxpool.Pool // nil with the memory driver
+		userRepo     domain.UserRepository
+		tokenRepo    domain.TokenRepository
+		lixiRepo     domain.LixiRepository
+		greetingRepo domain.LixiGreetingRepository
+		auditRepo    domain.AuditRepository
+		revisionRepo domain.LixiRevisionRepository
+		transactor   domain.Transactor
+		broadcaster  domain.GreetingBroadcaster
+		closeStreams func() // Ends live greeting streams on shutdown
+	)
+
+	switch cfg.Storage.Driver {
+	case "postgres":
+		pool, err = database.Connect(cfg.Database)
+		if err != nil {
+			fatal("Failed to connect to database", err)
 		}
-		log.Println("⚠️  WARNING: Using default JWT_SECRET for development only")
-		jwtSecret = "my_secret_key"
+
+		metrics.RegisterPoolStats(registry, pool)
+
+		// 2. Run Migrations
+		if err := database.RunMigrations(pool); err != nil {
+			fatal("Failed to run migrations", err)
+		}
+
+		userRepo = repository.NewPostgresUserRepository(pool)
+		tokenRepo = repository.NewPostgresTokenRepository(pool)
+		lixiRepo = repository.NewPostgresLixiRepository(pool)
+		greetingRepo = repository.NewPostgresLixiGreetingRepository(pool)
+		auditRepo = repository.NewPostgresAuditRepository(pool)
+		revisionRepo = repository.NewPostgresLixiRevisionRepository(pool)
+		transactor = database.NewTransactor(pool)
+
+		// Greeting wall events reach the streams of every instance via LISTEN/NOTIFY
+		pgBroadcaster := broadcast.NewPostgresBroadcaster(pool, greetingRepo)
+		pgBroadcaster.Start(context.Background())
+		broadcaster, closeStreams = pgBroadcaster, pgBroadcaster.Close
+	case "memory":
+		logger.Warn("Using in-memory storage, data will be lost on restart")
+		userRepo = repository.NewMemoryUserRepository()
+		tokenRepo = repository.NewMemoryTokenRepository()
+		lixiRepo = repository.NewMemoryLixiRepository()
+		greetingRepo = repository.NewMemoryLixiGreetingRepository()
+		auditRepo = repository.NewMemoryAuditRepository()
+		revisionRepo = repository.NewMemoryLixiRevisionRepository()
+		transactor = repository.NewMemoryTransactor()
+		hub := broadcast.NewHub()
+		broadcaster, closeStreams = hub, hub.Close
 	}
 
-	userRepo := repository.NewPostgresUserRepository()
-	authService := service.NewAuthService(userRepo, jwtSecret)
+	// 3. Init Dependencies
+	if cfg.Auth.JWTSecret == config.DevJWTSecret {
+		logger.Warn("Using default JWT_SECRET for development only")
+	}
+	if cfg.Lixi.DeviceSecret == config.DevDeviceSecret {
+		logger.Warn("Using default LIXI_DEVICE_SECRET for development only")
+	}
+
+	// Rate limits are per instance by default; the postgres driver shares them
+	limiter := repository.NewMemoryRateLimiter()
+	if cfg.RateLimit.Driver == "postgres" {
+		limiter = repository.NewPostgresRateLimiter(pool)
+	}
+	limitByIP := func(name string, rule config.RateLimitRule) func(http.Handler) http.Handler {
+		return handler.RateLimitByIP(limiter, cfg.Server.TrustProxyHeaders, name, rateLimit(rule))
+	}
+
+	authService := service.NewAuthService(userRepo, tokenRepo, auditRepo, transactor, limiter, rateLimit(cfg.RateLimit.LoginAccount), cfg.Auth.JWTSecret)
 	authHandler := handler.NewAuthHandler(authService)
 
 	// Init Lixi Dependencies
-	lixiRepo := repository.NewPostgresLixiRepository()
-	greetingRepo := repository.NewPostgresLixiGreetingRepository()
-	lixiService := service.NewLixiService(lixiRepo, greetingRepo)
+	imageStorage, err := newImageStorage(cfg.Images)
+	if err != nil {
+		fatal("Failed to init image storage", err)
+	}
+	lixiService := service.NewLixiService(lixiRepo, greetingRepo, auditRepo, revisionRepo, transactor, imageStorage, broadcaster, cfg.Lixi.DeviceSecret, cfg.Lixi.BannedWords)
+	lixiService = metrics.InstrumentLixiService(registry, lixiService)
+	metrics.RegisterActiveLixiConfig(registry, lixiRepo)
 	lixiHandler := handler.NewLixiHandler(lixiService)
+	auditHandler := handler.NewAuditHandler(service.NewAuditService(auditRepo))
+
+	// Start the scheduler that flips configs on/off at their starts_at/ends_at
+	lixiScheduler := service.NewLixiScheduler(lixiRepo, cfg.Lixi.SchedulerInterval)
+	lixiScheduler.Start(context.Background())
 
 	// Seed Admin User (ignore error if already exists)
-	_, err := authService.Register(context.Background(), "admin", "12345678@X")
+	_, err = authService.Register(context.Background(), "admin", "12345678@X")
 	if err != nil {
-		fmt.Printf("Admin user already exists or error: %v\n", err)
+		logger.Info("Admin user already exists or error", "error", err)
 	} else {
-		fmt.Println("Admin user created: admin / 12345678@X")
+		logger.Info("Admin user created", "email", "admin")
+	}
+	if err := authService.SetRole(context.Background(), "admin", domain.RoleAdmin); err != nil {
+		fatal("Failed to grant admin role to seeded admin user", err)
 	}
 
 	// 2. Setup Router
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("POST /register", authHandler.Register)
-	mux.HandleFunc("POST /login", authHandler.Login)
+	mux.Handle("POST /register", limitByIP("register", cfg.RateLimit.Register)(http.HandlerFunc(authHandler.Register)))
+	mux.Handle("POST /login", limitByIP("login", cfg.RateLimit.Login)(http.HandlerFunc(authHandler.Login)))
+	mux.Handle("POST /token/refresh", limitByIP("refresh", cfg.RateLimit.Refresh)(http.HandlerFunc(authHandler.Refresh)))
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	mux.Handle("GET /metrics", handler.RequireStaticToken(cfg.Metrics.Token)(registry.Handler()))
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Welcome to My Backend API"))
 	})
 
+	// Uploaded images (local storage only; S3 objects are served by the bucket)
+	if cfg.Images.Backend == "local" {
+		mux.Handle("GET /uploads/", http.StripPrefix("/uploads/", handler.ServeFiles(cfg.Images.UploadDir)))
+	}
+
 	// Lixi Routes - Public
 	mux.HandleFunc("GET /api/lixi/active", lixiHandler.GetActive)
-	mux.HandleFunc("POST /api/lixi/greeting", lixiHandler.SubmitGreeting)
+	mux.HandleFunc("GET /api/lixi/greetings", lixiHandler.GetPublicGreetings)
+	mux.HandleFunc("GET /api/lixi/greetings/stream", lixiHandler.StreamGreetings)
+	mux.Handle("POST /api/lixi/draw", limitByIP("draw", cfg.RateLimit.Draw)(handler.OptionalAuth(authService)(http.HandlerFunc(lixiHandler.Draw))))
+	mux.Handle("POST /api/lixi/device-token", limitByIP("device-token", cfg.RateLimit.DeviceToken)(http.HandlerFunc(lixiHandler.IssueDeviceToken)))
+	mux.Handle("POST /api/lixi/greeting", limitByIP("greeting", cfg.RateLimit.Greeting)(http.HandlerFunc(lixiHandler.SubmitGreeting)))
+
+	requireAuth := handler.RequireAuth(authService)
+	mux.Handle("POST /logout", requireAuth(http.HandlerFunc(authHandler.Logout)))
+
+	// Admin Routes (require a valid JWT carrying at least the given role)
+	protect := func(role domain.Role, h http.HandlerFunc) http.Handler {
+		return requireAuth(handler.RequireRole(role)(h))
+	}
 
-	// Lixi Routes - Admin
-	mux.HandleFunc("GET /api/admin/lixi", lixiHandler.GetAll)
-	mux.HandleFunc("POST /api/admin/lixi", lixiHandler.Create)
-	mux.HandleFunc("PUT /api/admin/lixi/{id}", lixiHandler.Update)
-	mux.HandleFunc("DELETE /api/admin/lixi/{id}", lixiHandler.Delete)
-	mux.HandleFunc("POST /api/admin/lixi/{id}/activate", lixiHandler.Activate)
-	mux.HandleFunc("GET /api/admin/lixi/greetings", lixiHandler.GetAllGreetings)
+	mux.Handle("GET /api/admin/lixi", protect(domain.RoleViewer, lixiHandler.GetAll))
+	mux.Handle("POST /api/admin/lixi", protect(domain.RoleEditor, lixiHandler.Create))
+	mux.Handle("GET /api/admin/lixi/{id}", protect(domain.RoleViewer, lixiHandler.Get))
+	mux.Handle("PUT /api/admin/lixi/{id}", protect(domain.RoleEditor, lixiHandler.Update))
+	mux.Handle("DELETE /api/admin/lixi/{id}", protect(domain.RoleAdmin, lixiHandler.Delete))
+	mux.Handle("POST /api/admin/lixi/{id}/activate", protect(domain.RoleAdmin, lixiHandler.Activate))
+	mux.Handle("GET /api/admin/lixi/{id}/stats", protect(domain.RoleViewer, lixiHandler.Stats))
+	mux.Handle("GET /api/admin/lixi/{id}/revisions", protect(domain.RoleViewer, lixiHandler.GetRevisions))
+	mux.Handle("GET /api/admin/lixi/{id}/revisions/diff", protect(domain.RoleViewer, lixiHandler.DiffRevisions))
+	mux.Handle("POST /api/admin/lixi/{id}/revisions/{rev}/restore", protect(domain.RoleEditor, lixiHandler.RestoreRevision))
+	mux.Handle("GET /api/admin/lixi/greetings", protect(domain.RoleViewer, lixiHandler.GetAllGreetings))
+	mux.Handle("POST /api/admin/lixi/greetings/approve", protect(domain.RoleEditor, lixiHandler.ModerateGreetings(domain.GreetingApproved)))
+	mux.Handle("POST /api/admin/lixi/greetings/reject", protect(domain.RoleEditor, lixiHandler.ModerateGreetings(domain.GreetingRejected)))
+	mux.Handle("POST /api/admin/lixi/greetings/hide", protect(domain.RoleEditor, lixiHandler.ModerateGreetings(domain.GreetingHidden)))
+	mux.Handle("PUT /api/admin/users/{email}/role", protect(domain.RoleAdmin, authHandler.SetRole))
+	mux.Handle("DELETE /api/admin/users/{email}/sessions", protect(domain.RoleAdmin, authHandler.RevokeSessions))
+	mux.Handle("GET /api/admin/audit", protect(domain.RoleAdmin, auditHandler.List))
 
 	// 3. Start Server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	addr := ":" + strconv.Itoa(cfg.Server.Port)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           withMiddleware(mux, logger, registry, cfg.Server.TrustProxyHeaders, cfg.CORS.AllowedOrigins),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+	// Shutdown waits for active requests, so end the never-ending streams
+	server.RegisterOnShutdown(closeStreams)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Server is running", "addr", "http://localhost"+addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var serveErr error
+	select {
+	case serveErr = <-serverErr:
+		logger.Error("Error starting server", "error", serveErr)
+	case <-ctx.Done():
+		logger.Info("Shutting down: draining in-flight requests")
 	}
-	addr := ":" + port
-	fmt.Printf("Server is running on http://localhost%s\n", addr)
+	stop() // A second signal kills the process immediately
 
-	// Add CORS middleware
-	handler := enableCORS(mux)
+	// 4. Shut down in dependency order: stop accepting and drain requests,
+	// then stop background jobs, and only then close the database
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
 
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		fmt.Println("Error starting server:", err)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Graceful shutdown incomplete, closing remaining connections", "error", err)
+		server.Close()
+	}
+	lixiScheduler.Stop()
+	database.Close(pool)
+	logger.Info("Server stopped")
+
+	if serveErr != nil {
+		os.Exit(1)
 	}
 }
 
-func enableCORS(next http.Handler) http.Handler {
-	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-	if allowedOrigins == "" {
-		if os.Getenv("ENV") == "production" {
-			log.Fatal("ALLOWED_ORIGINS must be set in production")
-		}
-		allowedOrigins = "http://localhost:3000,http://localhost:5000"
+// withMiddleware wraps the router with the middleware shared by every
+// request. RequestID comes first so that everything after it logs the ID, and
+// Recover sits inside AccessLog and Metrics so that recovered panics are
+// logged and counted as 500s.
+func withMiddleware(mux http.Handler, logger *slog.Logger, registry *metrics.Registry, trustProxyHeaders bool, allowedOrigins []string) http.Handler {
+	return handler.RequestID(logger, trustProxyHeaders)(
+		handler.AccessLog(
+			handler.Metrics(registry)(
+				handler.Recover(
+					enableCORS(mux, allowedOrigins),
+				),
+			),
+		),
+	)
+}
+
+// rateLimit converts a configured rule into the domain token bucket
+func rateLimit(rule config.RateLimitRule) domain.RateLimit {
+	return domain.RateLimit{Burst: rule.Burst, Per: rule.Per}
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// newImageStorage builds the greeting image storage selected by images.backend
+func newImageStorage(cfg config.ImagesConfig) (domain.ImageStorage, error) {
+	if cfg.Backend == "s3" {
+		return storage.NewS3ImageStorage(storage.S3Config{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PublicURL:       cfg.S3.PublicURL,
+		})
 	}
+	return storage.NewLocalImageStorage(cfg.UploadDir, cfg.UploadBaseURL)
+}
 
+func enableCORS(next http.Handler, allowedOrigins []string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 
 		// Check if origin is allowed
 		originAllowed := false
-		for _, allowed := range strings.Split(allowedOrigins, ",") {
-			if origin == strings.TrimSpace(allowed) {
+		for _, allowed := range allowedOrigins {
+			if origin == allowed {
 				originAllowed = true
 				break
 			}
@@ -126,7 +308,8 @@ func enableCORS(next http.Handler) http.Handler {
 		if originAllowed {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Device-Token, X-Request-ID, If-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After, ETag")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Max-Age", "3600")
 		}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"my_backend/internal/config"
+	"my_backend/internal/database"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: go run ./cmd/migrate <command>
+
+Commands:
+  up          Apply all pending migrations
+  down [n]    Roll back the last n migrations (default 1)
+  status      Show applied and pending migrations`
+
+func main() {
+	// Load .env file (ignore error if not found - production uses env vars)
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	// Exit only after run has returned, so its deferred pool close has run
+	if err := run(os.Args[1], os.Args[2:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+// errUsage reports an unknown command
+var errUsage = errors.New("unknown command")
+
+// run executes one migrate command
+func run(command string, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Storage.Driver != "postgres" {
+		return fmt.Errorf("migrations only apply to the postgres storage driver, not %q", cfg.Storage.Driver)
+	}
+
+	pool, err := database.Connect(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close(pool)
+
+	migrator, err := database.NewMigrator(pool)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		fmt.Printf("%d migration(s) applied\n", len(applied))
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[0])
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return fmt.Errorf("rollback failed: %w", err)
+		}
+		for _, m := range rolledBack {
+			fmt.Printf("Rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		fmt.Printf("%d migration(s) rolled back\n", len(rolledBack))
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read migration status: %w", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-32s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		return errUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package broadcast fans greeting wall events out to live subscribers such
+// as Server-Sent Events streams.
+package broadcast
+
+import (
+	"context"
+	"sync"
+
+	"my_backend/internal/domain"
+)
+
+const (
+	// subscriberBuffer is how many events a slow subscriber may lag behind
+	// before further events are dropped for it
+	subscriberBuffer = 16
+
+	// maxSubscribers bounds the open streams of one instance
+	maxSubscribers = 10_000
+)
+
+var errTooManySubscribers = domain.Unavailable("too many live connections, please try again later")
+
+// Hub delivers events to the subscribers of this process. On its own it is
+// the GreetingBroadcaster of a single instance.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan domain.GreetingEvent]struct{}
+	closed      bool
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[chan domain.GreetingEvent]struct{})}
+}
+
+// Publish delivers event to every subscriber without blocking
+func (h *Hub) Publish(ctx context.Context, event domain.GreetingEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default: // The subscriber is not keeping up; it can reload the wall
+		}
+	}
+	return nil
+}
+
+func (h *Hub) Subscribe() (<-chan domain.GreetingEvent, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, domain.Unavailable("server is shutting down")
+	}
+	if len(h.subscribers) >= maxSubscribers {
+		return nil, nil, errTooManySubscribers
+	}
+
+	ch := make(chan domain.GreetingEvent, subscriberBuffer)
+	h.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// Close ends every subscription, so that long-lived streams finish during a
+// graceful shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
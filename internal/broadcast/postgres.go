@@ -0,0 +1,144 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"my_backend/internal/database"
+	"my_backend/internal/domain"
+	"my_backend/internal/logging"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// notifyChannel is the Postgres channel carrying greeting wall events
+const notifyChannel = "lixi_greetings"
+
+// notification is the NOTIFY payload. It only carries the greeting ID since
+// payloads are limited to 8000 bytes; listeners load the greeting themselves.
+type notification struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// PostgresBroadcaster publishes events with NOTIFY and LISTENs for them, so
+// that subscribers on every API instance receive events from all of them
+type PostgresBroadcaster struct {
+	pool      *pgxpool.Pool
+	greetings domain.LixiGreetingRepository
+	hub       *Hub
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPostgresBroadcaster creates a broadcaster that listens once Start is called
+func NewPostgresBroadcaster(pool *pgxpool.Pool, greetings domain.LixiGreetingRepository) *PostgresBroadcaster {
+	return &PostgresBroadcaster{pool: pool, greetings: greetings, hub: NewHub()}
+}
+
+// Publish sends event to every instance, including this one. Inside a
+// transaction the notification is only delivered on commit.
+func (b *PostgresBroadcaster) Publish(ctx context.Context, event domain.GreetingEvent) error {
+	payload, err := json.Marshal(notification{Type: event.Type, ID: event.Greeting.ID})
+	if err != nil {
+		return err
+	}
+
+	if _, err := database.Conn(ctx, b.pool).Exec(ctx, "SELECT pg_notify($1, $2)", notifyChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify greeting event: %w", err)
+	}
+	return nil
+}
+
+func (b *PostgresBroadcaster) Subscribe() (<-chan domain.GreetingEvent, func(), error) {
+	return b.hub.Subscribe()
+}
+
+// Start listens for notifications in the background until Close, reconnecting
+// with backoff when the connection drops
+func (b *PostgresBroadcaster) Start(ctx context.Context) {
+	ctx, b.cancel = context.WithCancel(ctx)
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("component", "greeting_broadcaster"))
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		backoff := time.Second
+		for {
+			err := b.listen(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			logging.FromContext(ctx).Warn("greeting listener disconnected, retrying", "error", err, "backoff", backoff.String())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, 30*time.Second)
+		}
+	}()
+}
+
+// listen holds a dedicated connection on the channel and forwards
+// notifications to the local hub until the connection fails
+func (b *PostgresBroadcaster) listen(ctx context.Context) error {
+	conn, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// Take the connection out of the pool: it stays subscribed to the channel
+	// and is closed rather than reused when we are done
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+
+	for {
+		n, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		b.deliver(ctx, n.Payload)
+	}
+}
+
+func (b *PostgresBroadcaster) deliver(ctx context.Context, payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		logging.FromContext(ctx).Warn("ignoring malformed greeting notification", "payload", payload)
+		return
+	}
+
+	event := domain.GreetingEvent{Type: n.Type, Greeting: &domain.LixiGreeting{ID: n.ID}}
+	if n.Type == domain.GreetingEventAdded {
+		greeting, err := b.greetings.GetByID(ctx, n.ID)
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to load notified greeting", "greeting_id", n.ID, "error", err)
+			return
+		}
+		if greeting.Status != domain.GreetingApproved {
+			return // Moderated again since the notification was sent
+		}
+		event.Greeting = greeting
+	}
+
+	b.hub.Publish(ctx, event)
+}
+
+// Close stops listening and ends every subscription
+func (b *PostgresBroadcaster) Close() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.wg.Wait()
+	b.hub.Close()
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{
+			"url with password",
+			"postgres://app:s3cret@db:5432/lixi?sslmode=require",
+			"postgres://app:xxxxx@db:5432/lixi?sslmode=require",
+		},
+		{
+			"url without password",
+			"postgres://app@db/lixi",
+			"postgres://app@db/lixi",
+		},
+		{
+			"url with password in the query",
+			"postgres://db/lixi?user=app&password=s3cret&sslmode=disable",
+			"postgres://db/lixi?password=xxxxx&sslmode=disable&user=app",
+		},
+		{
+			"url with ssl key password",
+			"postgresql://app:s3cret@db/lixi?sslpassword=k3y",
+			"postgresql://app:xxxxx@db/lixi?sslpassword=xxxxx",
+		},
+		{
+			"unparsable url",
+			"postgres://app:s3cret@db:port/lixi",
+			redacted,
+		},
+		{
+			"keyword/value",
+			"host=db user=app password=s3cret dbname=lixi",
+			"host=db user=app password=xxxxx dbname=lixi",
+		},
+		{
+			"keyword/value with spaces and quotes",
+			`host=db password = 'it\'s a s3cret' dbname=lixi`,
+			"host=db password = xxxxx dbname=lixi",
+		},
+		{
+			"keyword/value case and ssl key password",
+			"host=db PASSWORD=s3cret sslpassword=k3y",
+			"host=db PASSWORD=xxxxx sslpassword=xxxxx",
+		},
+		{
+			"keyword/value without password",
+			"host=db user=app dbname=lixi",
+			"host=db user=app dbname=lixi",
+		},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactDSN(tt.dsn)
+			if got != tt.want {
+				t.Errorf("redactDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+			}
+			for _, secret := range []string{"s3cret", "k3y"} {
+				if strings.Contains(got, secret) {
+					t.Errorf("redactDSN(%q) leaks %q", tt.dsn, secret)
+				}
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.JWTSecret = "jwt-secret"
+	cfg.Lixi.DeviceSecret = "device-secret"
+	cfg.Images.S3.SecretAccessKey = "s3-secret"
+	cfg.Metrics.Token = "metrics-secret"
+	cfg.Database.URL = "host=db password=db-secret"
+	cfg.CORS.AllowedOrigins = []string{"https://example.com"}
+
+	out := cfg.String()
+	for _, secret := range []string{"jwt-secret", "device-secret", "s3-secret", "metrics-secret", "db-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("String() leaks %q:\n%s", secret, out)
+		}
+	}
+
+	// The original is left untouched
+	if cfg.Auth.JWTSecret != "jwt-secret" || cfg.Database.URL != "host=db password=db-secret" {
+		t.Error("Redacted modified the original config")
+	}
+}
+
+func TestValidateProductionRequiresMetricsToken(t *testing.T) {
+	cfg := Default()
+	cfg.Env = "production"
+	cfg.Storage.Driver = "postgres"
+	cfg.Database.URL = "postgres://app:s3cret@db/lixi"
+	cfg.Auth.JWTSecret = strings.Repeat("j", MinJWTSecretLength)
+	cfg.Lixi.DeviceSecret = strings.Repeat("d", MinJWTSecretLength)
+	cfg.CORS.AllowedOrigins = []string{"https://example.com"}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "METRICS_TOKEN") {
+		t.Fatalf("Validate() = %v, want a METRICS_TOKEN error", err)
+	}
+
+	cfg.Metrics.Token = "scrape-token"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with a metrics token = %v", err)
+	}
+
+	// Development may serve /metrics openly
+	cfg.Env, cfg.Metrics.Token = "development", ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() in development = %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS users (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	email TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- Existing users become viewers
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'viewer'
+CHECK (role IN ('viewer', 'editor', 'admin'));
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS lixi_configs (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	name TEXT NOT NULL,
+	envelopes JSONB NOT NULL,
+	is_active BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Ensure only one active config
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lixi_active
+ON lixi_configs (is_active) WHERE is_active = TRUE;
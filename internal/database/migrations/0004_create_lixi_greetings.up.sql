@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS lixi_greetings (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	name TEXT NOT NULL DEFAULT '',
+	amount TEXT NOT NULL,
+	message TEXT NOT NULL,
+	image TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
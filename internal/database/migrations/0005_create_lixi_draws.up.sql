@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS lixi_draws (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	config_id BIGINT NOT NULL REFERENCES lixi_configs(id) ON DELETE CASCADE,
+	envelope_id INT NOT NULL,
+	amount TEXT NOT NULL,
+	message TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_lixi_draws_config ON lixi_draws (config_id, created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE lixi_draws DROP COLUMN IF EXISTS value;
+
+ALTER TABLE lixi_configs
+DROP COLUMN IF EXISTS spent,
+DROP COLUMN IF EXISTS budget;
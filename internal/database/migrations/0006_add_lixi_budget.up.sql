@@ -0,0 +1,7 @@
+-- Envelope stock lives in the envelopes JSONB; the budget is tracked per config
+ALTER TABLE lixi_configs
+ADD COLUMN IF NOT EXISTS budget BIGINT NOT NULL DEFAULT 0 CHECK (budget >= 0),
+ADD COLUMN IF NOT EXISTS spent BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE lixi_draws
+ADD COLUMN IF NOT EXISTS value BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,46 @@
+-- Restore display strings; they remain parseable by domain.ParseMoney.
+-- The legacy strings can only hold whole đồng, so rows in any other currency
+-- would come back with their minor units read as đồng (100x for USD). Such
+-- data cannot be rolled back and is refused instead.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM lixi_draws WHERE currency <> 'VND')
+		OR EXISTS (SELECT 1 FROM lixi_greetings WHERE currency <> 'VND')
+		OR EXISTS (SELECT 1 FROM lixi_configs WHERE currency <> 'VND')
+		OR EXISTS (
+			SELECT 1 FROM lixi_configs, jsonb_array_elements(envelopes) AS e(value)
+			WHERE coalesce(e.value -> 'amount' ->> 'currency', 'VND') <> 'VND'
+		) THEN
+		RAISE EXCEPTION 'cannot roll back money amounts: rows in currencies other than VND exist';
+	END IF;
+END $$;
+
+ALTER TABLE lixi_draws RENAME COLUMN amount_minor TO value;
+ALTER TABLE lixi_draws ADD COLUMN amount TEXT NOT NULL DEFAULT '';
+UPDATE lixi_draws SET amount = value || ' ' || currency;
+ALTER TABLE lixi_draws ALTER COLUMN amount DROP DEFAULT;
+ALTER TABLE lixi_draws DROP COLUMN currency;
+
+ALTER TABLE lixi_greetings ADD COLUMN amount TEXT NOT NULL DEFAULT '';
+UPDATE lixi_greetings SET amount = amount_minor || ' ' || currency;
+ALTER TABLE lixi_greetings ALTER COLUMN amount DROP DEFAULT;
+ALTER TABLE lixi_greetings DROP COLUMN amount_minor, DROP COLUMN currency;
+
+UPDATE lixi_configs
+SET envelopes = (
+	SELECT jsonb_agg(
+		CASE WHEN jsonb_typeof(e.value -> 'amount') = 'object'
+			THEN jsonb_set(e.value, '{amount}', to_jsonb(coalesce(
+				e.value -> 'amount' ->> 'display',
+				(e.value -> 'amount' ->> 'amount') || ' ' || (e.value -> 'amount' ->> 'currency')
+			)))
+			ELSE e.value
+		END
+		ORDER BY e.ordinality
+	)
+	FROM jsonb_array_elements(envelopes) WITH ORDINALITY AS e(value, ordinality)
+);
+
+ALTER TABLE lixi_configs DROP COLUMN currency;
+
+DROP FUNCTION IF EXISTS lixi_parse_vnd(TEXT);
@@ -0,0 +1,75 @@
+-- Convert free-form amount strings ("100K VNĐ") to minor units + currency
+CREATE OR REPLACE FUNCTION lixi_parse_vnd(amount TEXT) RETURNS BIGINT AS $$
+DECLARE
+	s TEXT := trim(regexp_replace(lower(trim(amount)), '\s*(vnđ|vnd|đồng|đ)$', ''));
+	num TEXT := substring(s from '^[0-9.,]+');
+	unit TEXT := coalesce(trim(substring(s from '^[0-9.,]+\s*(.*)$')), '');
+	multiplier NUMERIC;
+BEGIN
+	multiplier := CASE unit
+		WHEN '' THEN 1
+		WHEN 'k' THEN 1000 WHEN 'nghìn' THEN 1000 WHEN 'ngàn' THEN 1000
+		WHEN 'tr' THEN 1000000 WHEN 'triệu' THEN 1000000 WHEN 'm' THEN 1000000
+		WHEN 'tỷ' THEN 1000000000 WHEN 'tỉ' THEN 1000000000 WHEN 'b' THEN 1000000000
+	END;
+	IF num IS NULL OR multiplier IS NULL THEN
+		RETURN 0;
+	END IF;
+	IF multiplier = 1 THEN
+		num := replace(replace(num, '.', ''), ',', '');
+	ELSE
+		num := replace(num, ',', '.');
+	END IF;
+	RETURN round(num::NUMERIC * multiplier)::BIGINT;
+EXCEPTION WHEN others THEN
+	RETURN 0;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+ALTER TABLE lixi_configs
+ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'VND';
+
+UPDATE lixi_configs
+SET envelopes = (
+	SELECT jsonb_agg(
+		CASE WHEN jsonb_typeof(e.value -> 'amount') = 'string'
+			THEN jsonb_set(e.value, '{amount}', jsonb_build_object(
+				'amount', lixi_parse_vnd(e.value ->> 'amount'),
+				'currency', 'VND'
+			))
+			ELSE e.value
+		END
+		ORDER BY e.ordinality
+	)
+	FROM jsonb_array_elements(envelopes) WITH ORDINALITY AS e(value, ordinality)
+)
+WHERE EXISTS (
+	SELECT 1 FROM jsonb_array_elements(envelopes) AS e(value)
+	WHERE jsonb_typeof(e.value -> 'amount') = 'string'
+);
+
+ALTER TABLE lixi_greetings
+ADD COLUMN IF NOT EXISTS amount_minor BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'VND';
+
+ALTER TABLE lixi_draws
+ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'VND';
+
+-- The checks keep this safe to replay on databases already converted by the
+-- previous inline migrations
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema()
+		AND table_name = 'lixi_greetings' AND column_name = 'amount') THEN
+		UPDATE lixi_greetings SET amount_minor = lixi_parse_vnd(amount);
+		ALTER TABLE lixi_greetings DROP COLUMN amount;
+	END IF;
+
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema()
+		AND table_name = 'lixi_draws' AND column_name = 'amount_minor') THEN
+		ALTER TABLE lixi_draws DROP COLUMN IF EXISTS value;
+	ELSE
+		ALTER TABLE lixi_draws DROP COLUMN IF EXISTS amount;
+		ALTER TABLE lixi_draws RENAME COLUMN value TO amount_minor;
+	END IF;
+END $$;
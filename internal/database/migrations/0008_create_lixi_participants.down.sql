@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_lixi_draws_participant;
+ALTER TABLE lixi_draws DROP COLUMN IF EXISTS participant_key;
+
+DROP TABLE IF EXISTS lixi_participants;
+
+ALTER TABLE lixi_configs DROP COLUMN IF EXISTS draws_per_participant;
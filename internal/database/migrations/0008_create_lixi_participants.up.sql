@@ -0,0 +1,17 @@
+ALTER TABLE lixi_configs
+ADD COLUMN IF NOT EXISTS draws_per_participant INT NOT NULL DEFAULT 1 CHECK (draws_per_participant > 0);
+
+CREATE TABLE IF NOT EXISTS lixi_participants (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	config_id BIGINT NOT NULL REFERENCES lixi_configs(id) ON DELETE CASCADE,
+	participant_key TEXT NOT NULL,
+	draws INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	last_draw_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (config_id, participant_key)
+);
+
+ALTER TABLE lixi_draws
+ADD COLUMN IF NOT EXISTS participant_key TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_lixi_draws_participant ON lixi_draws (config_id, participant_key);
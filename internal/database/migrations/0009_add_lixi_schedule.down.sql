@@ -0,0 +1,3 @@
+ALTER TABLE lixi_configs
+DROP COLUMN IF EXISTS ends_at,
+DROP COLUMN IF EXISTS starts_at;
@@ -0,0 +1,3 @@
+ALTER TABLE lixi_configs
+ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP WITH TIME ZONE;
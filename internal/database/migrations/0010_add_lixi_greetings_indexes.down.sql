@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_lixi_greetings_amount;
+DROP INDEX IF EXISTS idx_lixi_greetings_created_at;
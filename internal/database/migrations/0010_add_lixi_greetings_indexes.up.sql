@@ -0,0 +1,3 @@
+-- Keyset pagination of the admin greeting list
+CREATE INDEX IF NOT EXISTS idx_lixi_greetings_created_at ON lixi_greetings (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_lixi_greetings_amount ON lixi_greetings (currency, amount_minor, id);
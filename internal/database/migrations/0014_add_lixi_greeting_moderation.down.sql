@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_lixi_greetings_status_created_at;
+
+ALTER TABLE lixi_greetings
+DROP COLUMN IF EXISTS moderated_at,
+DROP COLUMN IF EXISTS moderated_by,
+DROP COLUMN IF EXISTS status;
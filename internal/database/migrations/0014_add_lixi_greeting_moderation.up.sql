@@ -0,0 +1,12 @@
+-- Greetings accepted before moderation existed stay visible; new ones wait
+-- for review
+ALTER TABLE lixi_greetings
+ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'approved'
+CHECK (status IN ('pending', 'approved', 'rejected', 'hidden')),
+ADD COLUMN IF NOT EXISTS moderated_by TEXT,
+ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE lixi_greetings ALTER COLUMN status SET DEFAULT 'pending';
+
+-- Listings filtered by status (the public one and the moderation queue)
+CREATE INDEX IF NOT EXISTS idx_lixi_greetings_status_created_at ON lixi_greetings (status, created_at, id);
@@ -0,0 +1,29 @@
+-- One immutable snapshot of the admin-editable fields per save of a config
+CREATE TABLE IF NOT EXISTS lixi_config_revisions (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	config_id BIGINT NOT NULL REFERENCES lixi_configs(id) ON DELETE CASCADE,
+	revision INT NOT NULL,
+	name TEXT NOT NULL,
+	envelopes JSONB NOT NULL,
+	budget BIGINT NOT NULL,
+	currency TEXT NOT NULL,
+	draws_per_participant INT NOT NULL,
+	starts_at TIMESTAMP WITH TIME ZONE,
+	ends_at TIMESTAMP WITH TIME ZONE,
+	restored_from INT,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (config_id, revision)
+);
+
+-- Existing configs start their history at their current state; drawn
+-- counters are server-managed and not part of a revision
+INSERT INTO lixi_config_revisions (config_id, revision, name, envelopes, budget, currency, draws_per_participant, starts_at, ends_at, created_at)
+SELECT c.id, 1, c.name,
+	COALESCE((
+		SELECT jsonb_agg(jsonb_set(e.value, '{drawn}', '0'::jsonb) ORDER BY e.ordinality)
+		FROM jsonb_array_elements(c.envelopes) WITH ORDINALITY AS e(value, ordinality)
+	), '[]'::jsonb),
+	c.budget, c.currency, c.draws_per_participant, c.starts_at, c.ends_at, COALESCE(c.created_at, CURRENT_TIMESTAMP)
+FROM lixi_configs c
+WHERE NOT EXISTS (SELECT 1 FROM lixi_config_revisions r WHERE r.config_id = c.id);
@@ -0,0 +1,2 @@
+ALTER TABLE lixi_configs
+DROP COLUMN IF EXISTS version;
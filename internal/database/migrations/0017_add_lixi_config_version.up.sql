@@ -0,0 +1,3 @@
+-- Bumped by every admin change, for optimistic concurrency (ETag/If-Match)
+ALTER TABLE lixi_configs
+ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,13 @@
+-- The old schema has no role without admin access; rather than silently
+-- promoting such accounts to viewer, refuse until an admin has dealt with them
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM users WHERE role = 'user') THEN
+		RAISE EXCEPTION 'users with role "user" exist; grant them a role or delete them first';
+	END IF;
+END $$;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users
+ADD CONSTRAINT users_role_check CHECK (role IN ('viewer', 'editor', 'admin')),
+ALTER COLUMN role SET DEFAULT 'viewer';
@@ -0,0 +1,5 @@
+-- Accounts get no admin access until an admin grants them a role
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users
+ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'viewer', 'editor', 'admin')),
+ALTER COLUMN role SET DEFAULT 'user';
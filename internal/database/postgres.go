@@ -3,63 +3,54 @@ package database
 import (
 	"context"
 	"fmt"
-	"os"
-	"time"
+	"log/slog"
+
+	"my_backend/internal/config"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// DB holds the database connection pool
-var DB *pgxpool.Pool
-
-// Connect establishes a connection to the PostgreSQL database
-func Connect() error {
-	// Get DATABASE_URL from environment
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		return fmt.Errorf("DATABASE_URL environment variable is not set")
+// Connect opens a connection pool to the PostgreSQL database
+func Connect(cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("DATABASE_URL environment variable is not set")
 	}
 
 	// Configure connection pool
-	config, err := pgxpool.ParseConfig(databaseURL)
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
 	if err != nil {
-		return fmt.Errorf("unable to parse DATABASE_URL: %w", err)
+		return nil, fmt.Errorf("unable to parse DATABASE_URL: %w", err)
 	}
 
 	// Set pool configuration
-	config.MaxConns = 10
-	config.MinConns = 2
-	config.MaxConnLifetime = time.Hour
-	config.MaxConnIdleTime = 30 * time.Minute
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
 
 	// Create connection pool
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	defer cancel()
 
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		return fmt.Errorf("unable to create connection pool: %w", err)
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
 	// Verify connection
 	if err := pool.Ping(ctx); err != nil {
-		return fmt.Errorf("unable to ping database: %w", err)
+		pool.Close()
+		return nil, fmt.Errorf("unable to ping database: %w", err)
 	}
 
-	DB = pool
-	fmt.Println("✅ Connected to PostgreSQL database successfully!")
-	return nil
+	slog.Info("Connected to PostgreSQL database")
+	return pool, nil
 }
 
 // Close closes the database connection pool
-func Close() {
-	if DB != nil {
-		DB.Close()
-		fmt.Println("Database connection closed")
+func Close(pool *pgxpool.Pool) {
+	if pool != nil {
+		pool.Close()
+		slog.Info("Database connection closed")
 	}
 }
-
-// GetDB returns the database connection pool
-func GetDB() *pgxpool.Pool {
-	return DB
-}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Audited actions
+const (
+	AuditConfigCreate     = "lixi_config.create"
+	AuditConfigUpdate     = "lixi_config.update"
+	AuditConfigDelete     = "lixi_config.delete"
+	AuditConfigRestore    = "lixi_config.restore"
+	AuditConfigActivate   = "lixi_config.activate"
+	AuditConfigDeactivate = "lixi_config.deactivate" // Recorded for the config replaced by an activation
+	AuditUserSetRole      = "user.set_role"
+	AuditUserRevoke       = "user.revoke_sessions"
+)
+
+// Audit target types
+const (
+	AuditTargetLixiConfig = "lixi_config"
+	AuditTargetUser       = "user"
+)
+
+// AuditEntry records one admin change. Entries are never updated or deleted.
+type AuditEntry struct {
+	ID         string                 `json:"id"`
+	ActorID    string                 `json:"actor_id"` // Empty for changes made by the server itself
+	Actor      string                 `json:"actor"`    // Email of the actor
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Changes    map[string]AuditChange `json:"changes"` // Keyed by JSON path, e.g. "envelopes[2].rate"
+	RequestID  string                 `json:"request_id"`
+	IP         string                 `json:"ip"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditChange is the JSON value of a field before and after a change; a
+// missing side (field added or removed) is null
+type AuditChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Page size bounds for the audit log
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+)
+
+// AuditFilter selects a page of the audit log, newest first. Zero-valued
+// fields don't filter.
+type AuditFilter struct {
+	TargetType string
+	TargetID   string     // Requires TargetType
+	Actor      string     // Email of the actor
+	From       *time.Time // Inclusive
+	To         *time.Time // Exclusive
+	Cursor     string     // NextCursor of the previous page
+	Limit      int
+}
+
+// AuditPage is one page of the audit log
+type AuditPage struct {
+	Entries    []*AuditEntry `json:"entries"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *AuditEntry) error
+	List(ctx context.Context, filter AuditFilter) (*AuditPage, error)
+}
+
+type AuditService interface {
+	List(ctx context.Context, filter AuditFilter) (*AuditPage, error)
+}
+
+// RequestInfo identifies the HTTP request a change is made for
+type RequestInfo struct {
+	ID string // X-Request-ID
+	IP string // Client address
+}
+
+const requestInfoContextKey contextKey = "request_info"
+
+// ContextWithRequestInfo returns a copy of ctx carrying info
+func ContextWithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoContextKey, info)
+}
+
+// RequestInfoFromContext returns the request info stored in ctx; it is zero
+// outside of HTTP requests
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoContextKey).(RequestInfo)
+	return info
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "errors"
+
+// Error kinds. Every *Error wraps one of them, so callers can branch on the
+// kind with errors.Is without knowing the individual messages.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrPrecondition = errors.New("precondition failed") // The client's copy of a resource is stale
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnavailable  = errors.New("temporarily unavailable")
+)
+
+// Error is an error meant to be shown to the client, classified by Kind
+type Error struct {
+	Kind    error
+	Message string
+	Fields  map[string]string // Validation message per request field
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NotFound(message string) *Error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: ErrPrecondition, Message: message}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+func Unavailable(message string) *Error {
+	return &Error{Kind: ErrUnavailable, Message: message}
+}
+
+// Invalid reports a validation failure of a single request field
+func Invalid(field, message string) *Error {
+	return &Error{Kind: ErrValidation, Message: message, Fields: map[string]string{field: message}}
+}
+
+// Errors compared by identity across layers
+var (
+	ErrUserNotFound       = NotFound("user not found")
+	ErrUserExists         = Conflict("user already exists")
+	ErrLixiConfigNotFound = NotFound("lixi config not found")
+	ErrLixiConfigModified = PreconditionFailed("lixi config was modified in the meantime, reload it and retry")
+	ErrNoActiveLixiConfig = NotFound("no active lixi config found")
+)
@@ -5,19 +5,98 @@ import (
 	"time"
 )
 
+// ErrEnvelopeUnavailable is returned by LixiRepository.RecordDraw when the
+// picked envelope ran out of stock or would exceed the budget in the meantime
+var ErrEnvelopeUnavailable = Conflict("envelope is no longer available")
+
+// ErrDrawLimitReached is returned by LixiRepository.RecordDraw when the
+// participant already used all draws allowed by the config
+var ErrDrawLimitReached = Conflict("draw limit reached for this campaign")
+
+// DrawLimitError is returned by LixiService.Draw when the participant already
+// used all their draws; it carries their previous results
+type DrawLimitError struct {
+	Previous []*LixiDraw
+}
+
+func (e *DrawLimitError) Error() string {
+	return ErrDrawLimitReached.Error()
+}
+
+func (e *DrawLimitError) Unwrap() error {
+	return ErrDrawLimitReached
+}
+
 type LixiEnvelope struct {
-	ID      int     `json:"id"`
-	Amount  string  `json:"amount"`  // "100K VNĐ", "1 Triệu VNĐ"
-	Message string  `json:"message"` // "Phát Tài Phát Lộc!"
-	Rate    float64 `json:"rate"`    // Probability weight (e.g., 0.5 = 50% chance)
+	ID       int     `json:"id"`
+	Amount   Money   `json:"amount"`   // Rendered as "100K VNĐ", "1 Triệu VNĐ"
+	Message  string  `json:"message"`  // "Phát Tài Phát Lộc!"
+	Rate     float64 `json:"rate"`     // Probability weight (e.g., 0.5 = 50% chance)
+	Quantity int     `json:"quantity"` // Stock of this envelope, 0 = unlimited
+	Drawn    int     `json:"drawn"`    // Times this envelope has been won (server-managed)
+}
+
+// InStock reports whether the envelope can still be won
+func (e LixiEnvelope) InStock() bool {
+	return e.Quantity == 0 || e.Drawn < e.Quantity
 }
 
 type LixiConfig struct {
-	ID        string          `json:"id"`
-	Name      string          `json:"name"`      // "Tết 2025"
-	Envelopes []LixiEnvelope  `json:"envelopes"` // 12 envelopes
-	IsActive  bool            `json:"is_active"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID                  string         `json:"id"`
+	Name                string         `json:"name"`      // "Tết 2025"
+	Envelopes           []LixiEnvelope `json:"envelopes"` // 12 envelopes
+	IsActive            bool           `json:"is_active"`
+	Budget              Money          `json:"budget"`                // Total payout cap, zero = unlimited
+	Spent               Money          `json:"spent"`                 // Total paid out so far (server-managed)
+	DrawsPerParticipant int            `json:"draws_per_participant"` // Envelopes each participant may open
+	StartsAt            *time.Time     `json:"starts_at"`             // Scheduled activation, nil = manual
+	EndsAt              *time.Time     `json:"ends_at"`               // Scheduled deactivation, nil = open-ended
+	Version             int            `json:"version"`               // Bumped by every admin change, sent as the ETag
+	CreatedAt           time.Time      `json:"created_at"`
+}
+
+// AnyVersion makes a versioned LixiRepository or LixiService call skip the
+// version check (If-Match: *)
+const AnyVersion = 0
+
+// InWindow reports whether now falls inside the config's schedule; configs
+// without bounds are always in their window
+func (c *LixiConfig) InWindow(now time.Time) bool {
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// LixiSchedule is the activation window of a config; nil bounds are open
+type LixiSchedule struct {
+	StartsAt *time.Time
+	EndsAt   *time.Time
+}
+
+// DefaultDrawsPerParticipant is used when a config is created without a limit
+const DefaultDrawsPerParticipant = 1
+
+// LixiConfigInput carries the admin-editable fields of a LixiConfig.
+// UpdateConfig leaves nil/empty fields unchanged.
+type LixiConfigInput struct {
+	Name                string
+	Budget              *Money
+	DrawsPerParticipant *int
+	Schedule            *LixiSchedule // Replaces both bounds when non-nil
+	Envelopes           []LixiEnvelope
+}
+
+// Participant identifies who is drawing. The first non-empty field wins, in
+// the order UserID, Phone, Email, DeviceToken.
+type Participant struct {
+	UserID      string // Authenticated user
+	Phone       string
+	Email       string
+	DeviceToken string // Issued by LixiService.IssueDeviceToken
 }
 
 type LixiRepository interface {
@@ -25,32 +104,276 @@ type LixiRepository interface {
 	GetActive(ctx context.Context) (*LixiConfig, error)
 	GetByID(ctx context.Context, id string) (*LixiConfig, error)
 	GetAll(ctx context.Context) ([]*LixiConfig, error)
+	// Update saves config if its Version is still current
+	// (ErrLixiConfigModified otherwise) and bumps config.Version
 	Update(ctx context.Context, config *LixiConfig) error
-	Delete(ctx context.Context, id string) error
-	SetActive(ctx context.Context, id string) error
+	// Delete and SetActive fail with ErrLixiConfigModified unless the config
+	// is at version; SetActive bumps the version of every config it changes
+	Delete(ctx context.Context, id string, version int) error
+	SetActive(ctx context.Context, id string, version int) error
+	Deactivate(ctx context.Context, id string) error
+	// RecordDraw atomically counts the draw against the participant limit
+	// (ErrDrawLimitReached) and consumes stock and budget (ErrEnvelopeUnavailable)
+	RecordDraw(ctx context.Context, draw *LixiDraw) error
+	GetParticipantDraws(ctx context.Context, configID, participantKey string) ([]*LixiDraw, error)
+	GetDrawStats(ctx context.Context, configID string) (*DrawStats, error)
 }
 
 type LixiService interface {
-	CreateConfig(ctx context.Context, name string, envelopes []LixiEnvelope) (*LixiConfig, error)
+	CreateConfig(ctx context.Context, input LixiConfigInput) (*LixiConfig, error)
 	GetActiveConfig(ctx context.Context) (*LixiConfig, error)
 	GetAllConfigs(ctx context.Context) ([]*LixiConfig, error)
-	UpdateConfig(ctx context.Context, id string, name string, envelopes []LixiEnvelope) (*LixiConfig, error)
-	DeleteConfig(ctx context.Context, id string) error
-	SetActiveConfig(ctx context.Context, id string) error
-	SubmitGreeting(ctx context.Context, name, amount, message, image string) (*LixiGreeting, error)
-	GetAllGreetings(ctx context.Context) ([]*LixiGreeting, error)
+	GetConfig(ctx context.Context, id string) (*LixiConfig, error)
+	// UpdateConfig, DeleteConfig and SetActiveConfig fail with
+	// ErrLixiConfigModified unless the config is at version
+	UpdateConfig(ctx context.Context, id string, version int, input LixiConfigInput) (*LixiConfig, error)
+	DeleteConfig(ctx context.Context, id string, version int) error
+	SetActiveConfig(ctx context.Context, id string, version int) (*LixiConfig, error)
+	SubmitGreeting(ctx context.Context, name string, amount Money, message string, image ImageUpload) (*LixiGreeting, error)
+	GetAllGreetings(ctx context.Context, filter GreetingFilter) (*GreetingPage, error)
+	GetPublicGreetings(ctx context.Context, cursor string, limit int) (*GreetingPage, error) // Approved greetings only, newest first
+	SubscribeGreetings() (<-chan GreetingEvent, func(), error)
+	ModerateGreetings(ctx context.Context, ids []string, status GreetingStatus, moderator string) (*ModerationResult, error)
+	Draw(ctx context.Context, participant Participant) (*LixiDraw, error) // *DrawLimitError when the participant has no draws left
+	IssueDeviceToken(ctx context.Context) (string, error)
+	GetConfigStats(ctx context.Context, id string) (*LixiStats, error)
+	GetConfigRevisions(ctx context.Context, id string) ([]*LixiConfigRevision, error) // Newest first
+	// DiffConfigRevisions compares two revisions; to defaults to the latest
+	// revision and from to the one before to
+	DiffConfigRevisions(ctx context.Context, id string, from, to int) (*RevisionDiff, error)
+	// RestoreConfigRevision saves the revision's fields as a new revision and,
+	// like UpdateConfig, fails with ErrLixiConfigModified unless the config
+	// is at version
+	RestoreConfigRevision(ctx context.Context, id string, version, revision int) (*LixiConfig, error)
+}
+
+// LixiConfigRevision is an immutable snapshot of the admin-editable fields
+// of a config, taken on every save. Revisions are numbered from 1 per config.
+type LixiConfigRevision struct {
+	ConfigID            string         `json:"config_id"`
+	Revision            int            `json:"revision"`
+	Name                string         `json:"name"`
+	Envelopes           []LixiEnvelope `json:"envelopes"` // Drawn is always 0
+	Budget              Money          `json:"budget"`
+	DrawsPerParticipant int            `json:"draws_per_participant"`
+	StartsAt            *time.Time     `json:"starts_at"`
+	EndsAt              *time.Time     `json:"ends_at"`
+	RestoredFrom        *int           `json:"restored_from,omitempty"` // Set when saved by a restore
+	CreatedBy           string         `json:"created_by"`              // Email of the admin, empty for server changes
+	CreatedAt           time.Time      `json:"created_at"`
+}
+
+// ErrRevisionNotFound is returned when a config has no such revision
+var ErrRevisionNotFound = NotFound("revision not found")
+
+type LixiRevisionRepository interface {
+	// Create numbers revision as the next one of its config
+	Create(ctx context.Context, revision *LixiConfigRevision) error
+	List(ctx context.Context, configID string) ([]*LixiConfigRevision, error) // Newest first
+	Get(ctx context.Context, configID string, revision int) (*LixiConfigRevision, error)
+}
+
+// RevisionDiff lists what changed between two revisions of a config
+type RevisionDiff struct {
+	ConfigID  string           `json:"config_id"`
+	From      int              `json:"from"`
+	To        int              `json:"to"`
+	Changes   []FieldChange    `json:"changes"`   // Config-level fields
+	Envelopes []EnvelopeChange `json:"envelopes"` // Only envelopes that changed
+}
+
+// FieldChange is the value of a field in the from and to revisions; a
+// missing side is null
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// EnvelopeChange lists the changed fields (amount, message, rate, quantity)
+// of one envelope
+type EnvelopeChange struct {
+	EnvelopeID int           `json:"envelope_id"`
+	Changes    []FieldChange `json:"changes"`
+}
+
+// DrawStats aggregates the recorded draws of a config
+type DrawStats struct {
+	Envelopes    []EnvelopeDrawCount // Only envelopes drawn at least once, by ID
+	Participants int                 // Distinct participant keys
+}
+
+// EnvelopeDrawCount is the number of draws of one envelope and their payout
+// in minor units of the config currency
+type EnvelopeDrawCount struct {
+	EnvelopeID int
+	Draws      int
+	PaidOut    int64
+}
+
+// LixiStats is the admin view of a running config: how its envelopes are
+// being drawn and how many greetings come in during its window
+type LixiStats struct {
+	ConfigID           string          `json:"config_id"`
+	TotalDraws         int             `json:"total_draws"`
+	UniqueParticipants int             `json:"unique_participants"`
+	TotalPaidOut       Money           `json:"total_paid_out"`
+	Budget             Money           `json:"budget"` // Zero = unlimited
+	Envelopes          []EnvelopeStats `json:"envelopes"`
+	GreetingsFrom      time.Time       `json:"greetings_from"` // Window of the greeting figures
+	GreetingsTo        time.Time       `json:"greetings_to"`
+	GreetingsPerHour   []HourlyCount   `json:"greetings_per_hour"` // Hours without greetings are omitted
+	TopMessages        []MessageCount  `json:"top_messages"`
+	GeneratedAt        time.Time       `json:"generated_at"`
+}
+
+// EnvelopeStats compares how often an envelope was drawn with its configured
+// Rate; both rates are shares of the whole config, between 0 and 1
+type EnvelopeStats struct {
+	EnvelopeID     int     `json:"envelope_id"`
+	Amount         Money   `json:"amount"`
+	Message        string  `json:"message"`
+	Draws          int     `json:"draws"`
+	PaidOut        Money   `json:"paid_out"`
+	Remaining      *int    `json:"remaining"` // nil = unlimited stock
+	ConfiguredRate float64 `json:"configured_rate"`
+	ObservedRate   float64 `json:"observed_rate"`
+}
+
+// HourlyCount is the number of items created in the hour starting at Hour
+type HourlyCount struct {
+	Hour  time.Time `json:"hour"`
+	Count int       `json:"count"`
+}
+
+// MessageCount is how many times a message was sent, ignoring case and
+// surrounding spaces
+type MessageCount struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// TopMessagesLimit is the number of messages reported in LixiStats
+const TopMessagesLimit = 10
+
+// LixiDraw is the server-side result of opening one envelope of a config
+type LixiDraw struct {
+	ID             string    `json:"id"`
+	ConfigID       string    `json:"config_id"`
+	EnvelopeID     int       `json:"envelope_id"`
+	Amount         Money     `json:"amount"` // Payout counted against the config budget
+	Message        string    `json:"message"`
+	ParticipantKey string    `json:"-"` // e.g. "user:42", "phone:0901234567", "device:<id>"
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type LixiGreeting struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Amount    string    `json:"amount"`
-	Message   string    `json:"message"`
-	Image     string    `json:"image"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Amount      Money          `json:"amount"`
+	Message     string         `json:"message"`
+	Image       string         `json:"image"` // URL of the stored image
+	Status      GreetingStatus `json:"status"`
+	ModeratedBy string         `json:"moderated_by,omitempty"` // Email of the moderator who set Status
+	ModeratedAt *time.Time     `json:"moderated_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// GreetingStatus is the moderation state of a greeting. Greetings start out
+// pending and only approved ones are shown publicly.
+type GreetingStatus string
+
+const (
+	GreetingPending  GreetingStatus = "pending"
+	GreetingApproved GreetingStatus = "approved"
+	GreetingRejected GreetingStatus = "rejected" // Declined on review
+	GreetingHidden   GreetingStatus = "hidden"   // Taken down after having been approved
+)
+
+// Valid reports whether s is one of the known statuses
+func (s GreetingStatus) Valid() bool {
+	switch s {
+	case GreetingPending, GreetingApproved, GreetingRejected, GreetingHidden:
+		return true
+	}
+	return false
+}
+
+// MaxModerationBatch bounds the greetings moderated in one request
+const MaxModerationBatch = 100
+
+// ModerationResult reports which greetings of a bulk moderation were updated
+type ModerationResult struct {
+	Updated  []string `json:"updated"`
+	NotFound []string `json:"not_found"`
+}
+
+// Greeting listing sort keys; both are paginated by keyset on (key, id)
+const (
+	GreetingSortCreatedAt = "created_at"
+	GreetingSortAmount    = "amount"
+)
+
+// Page size bounds for greeting listings
+const (
+	DefaultGreetingPageSize = 20
+	MaxGreetingPageSize     = 100
+)
+
+// GreetingFilter selects and orders a page of greetings. Zero-valued fields
+// don't filter.
+type GreetingFilter struct {
+	Name      string         // Case-insensitive substring of the sender name
+	Status    GreetingStatus // Empty matches every status
+	Currency  string         // Required with MinAmount/MaxAmount
+	MinAmount *int64         // Inclusive, in minor units of Currency
+	MaxAmount *int64         // Inclusive, in minor units of Currency
+	From      *time.Time     // Inclusive lower bound on created_at
+	To        *time.Time     // Exclusive upper bound on created_at
+	SortBy    string         // GreetingSortCreatedAt (default) or GreetingSortAmount
+	Ascending bool           // Newest/largest first unless set
+	Cursor    string         // NextCursor of the previous page
+	Limit     int
+}
+
+// GreetingPage is one page of a greeting listing
+type GreetingPage struct {
+	Greetings  []*LixiGreeting `json:"greetings"`
+	NextCursor string          `json:"next_cursor,omitempty"` // Empty on the last page
+	Total      int             `json:"total"`                 // Matches across all pages
 }
 
 type LixiGreetingRepository interface {
 	Create(ctx context.Context, greeting *LixiGreeting) error
-	GetAll(ctx context.Context) ([]*LixiGreeting, error)
+	GetByID(ctx context.Context, id string) (*LixiGreeting, error)
+	GetAll(ctx context.Context, filter GreetingFilter) (*GreetingPage, error)
+	SetStatus(ctx context.Context, ids []string, status GreetingStatus, moderator string) ([]*LixiGreeting, error) // Returns the greetings that exist, updated
+	// CountPerHour counts the greetings created in [from, to) by hour
+	CountPerHour(ctx context.Context, from, to time.Time) ([]HourlyCount, error)
+	// TopMessages returns the most frequent messages of approved greetings created in [from, to)
+	TopMessages(ctx context.Context, from, to time.Time, limit int) ([]MessageCount, error)
+}
+
+// ErrGreetingNotFound is returned when no greeting has the requested ID
+var ErrGreetingNotFound = NotFound("greeting not found")
+
+// Greeting wall event types, also used as SSE event names
+const (
+	GreetingEventAdded   = "greeting"         // A greeting was approved
+	GreetingEventRemoved = "greeting_removed" // An approved greeting was hidden or rejected
+)
+
+// GreetingEvent is a change to the public greeting wall
+type GreetingEvent struct {
+	Type     string
+	Greeting *LixiGreeting // Only ID is set for GreetingEventRemoved
+}
+
+// GreetingBroadcaster fans greeting wall events out to every subscriber, on
+// this and (depending on the implementation) other API instances
+type GreetingBroadcaster interface {
+	Publish(ctx context.Context, event GreetingEvent) error
+	// Subscribe returns a channel of events, closed when the broadcaster shuts
+	// down, and a function to stop receiving them
+	Subscribe() (<-chan GreetingEvent, func(), error)
 }
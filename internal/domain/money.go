@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const CurrencyVND = "VND"
+
+// currencyExponents maps supported ISO 4217 codes to their number of minor-unit digits
+var currencyExponents = map[string]int{
+	"VND": 0,
+	"USD": 2,
+	"EUR": 2,
+}
+
+// Money is an amount expressed in integer minor units of an ISO 4217 currency
+// (đồng for VND, cents for USD), so totals never suffer from rounding
+type Money struct {
+	Amount   int64  `json:"amount"`   // Minor units
+	Currency string `json:"currency"` // ISO 4217 code, e.g. "VND"
+}
+
+// VND returns an amount of Vietnamese đồng
+func VND(amount int64) Money {
+	return Money{Amount: amount, Currency: CurrencyVND}
+}
+
+// IsZero reports whether m has no amount
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// ValidCurrency reports whether m uses a supported currency
+func (m Money) ValidCurrency() bool {
+	_, ok := currencyExponents[m.Currency]
+	return ok
+}
+
+// String renders m for display: "100K VNĐ", "1 Triệu VNĐ", "1,5 Tỷ VNĐ" for
+// VND and "12.50 USD" for currencies with minor units
+func (m Money) String() string {
+	if m.Currency != CurrencyVND {
+		exponent := currencyExponents[m.Currency]
+		value := float64(m.Amount) / math.Pow10(exponent)
+		return strings.TrimSpace(strconv.FormatFloat(value, 'f', exponent, 64) + " " + m.Currency)
+	}
+
+	for _, unit := range []struct {
+		size  int64
+		label string
+	}{
+		{1_000_000_000, " Tỷ"},
+		{1_000_000, " Triệu"},
+		{1_000, "K"},
+	} {
+		// Only use a unit when it is exact with at most two decimals
+		if m.Amount >= unit.size && m.Amount%(unit.size/100) == 0 {
+			value := strconv.FormatFloat(float64(m.Amount)/float64(unit.size), 'f', -1, 64)
+			return strings.ReplaceAll(value, ".", ",") + unit.label + " VNĐ"
+		}
+	}
+
+	return groupThousands(m.Amount) + " VNĐ"
+}
+
+// groupThousands formats n with "." thousands separators ("1.234.567")
+func groupThousands(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
+
+type moneyJSON struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Display  string `json:"display,omitempty"`
+}
+
+// MarshalJSON includes the display string next to the numeric amount
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{
+		Amount:   m.Amount,
+		Currency: m.Currency,
+		Display:  m.String(),
+	})
+}
+
+// UnmarshalJSON accepts {"amount": 100000, "currency": "VND"}, a bare number
+// of đồng, or a legacy display string such as "100K VNĐ"
+func (m *Money) UnmarshalJSON(data []byte) error {
+	switch {
+	case string(data) == "null":
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			*m = Money{}
+			return nil
+		}
+		parsed, err := ParseMoney(s)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case len(data) > 0 && data[0] == '{':
+		var v moneyJSON
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		if v.Currency == "" {
+			v.Currency = CurrencyVND
+		}
+		*m = Money{Amount: v.Amount, Currency: strings.ToUpper(v.Currency)}
+		return nil
+	default:
+		var amount int64
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return errors.New("money must be an object, a number or a string like \"100K VNĐ\"")
+		}
+		*m = VND(amount)
+		return nil
+	}
+}
+
+var vndUnits = map[string]float64{
+	"":      1,
+	"k":     1_000,
+	"nghìn": 1_000,
+	"ngàn":  1_000,
+	"tr":    1_000_000,
+	"triệu": 1_000_000,
+	"m":     1_000_000,
+	"tỷ":    1_000_000_000,
+	"tỉ":    1_000_000_000,
+	"b":     1_000_000_000,
+}
+
+// ParseMoney parses the legacy free-form VND strings such as "100K VNĐ",
+// "1 Triệu VNĐ", "1,5 Triệu" or "500.000đ"
+func ParseMoney(amount string) (Money, error) {
+	invalid := fmt.Errorf("invalid amount %q: expected a VND value like \"100K VNĐ\"", amount)
+
+	s := strings.ToLower(strings.TrimSpace(amount))
+	for _, suffix := range []string{"vnđ", "vnd", "đồng", "đ"} {
+		s = strings.TrimSpace(strings.TrimSuffix(s, suffix))
+	}
+
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ','
+	})
+	if end == -1 {
+		end = len(s)
+	}
+	number, unit := s[:end], strings.TrimSpace(s[end:])
+
+	multiplier, ok := vndUnits[unit]
+	if !ok || number == "" {
+		return Money{}, invalid
+	}
+
+	if multiplier == 1 {
+		// Without a unit, "." and "," are thousands separators ("500.000")
+		number = strings.NewReplacer(".", "", ",", "").Replace(number)
+	} else {
+		// With a unit they mark decimals ("1,5 Triệu")
+		number = strings.ReplaceAll(number, ",", ".")
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value <= 0 {
+		return Money{}, invalid
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63, which no longer fits
+	total := math.Round(value * multiplier)
+	if total >= math.MaxInt64 {
+		return Money{}, invalid
+	}
+	return VND(int64(total)), nil
+}
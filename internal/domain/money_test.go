@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64 // đồng; 0 means the input is rejected
+	}{
+		{"100K VNĐ", 100_000},
+		{"100k", 100_000},
+		{"100 nghìn", 100_000},
+		{"100 ngàn đồng", 100_000},
+		{"1 Triệu VNĐ", 1_000_000},
+		{"1,5 Triệu", 1_500_000},
+		{"1.5 Triệu", 1_500_000},
+		{"2tr", 2_000_000},
+		{"0,5M", 500_000},
+		{"1 Tỷ VNĐ", 1_000_000_000},
+		{"1,25 tỉ", 1_250_000_000},
+		{"500.000đ", 500_000},
+		{"500,000 VND", 500_000},
+		{"  20000  ", 20_000},
+		{"10000000000 tỷ", 0}, // Overflows int64
+		{"99999999999999999999", 0},
+		{"-100K", 0},
+		{"0K", 0},
+		{"0", 0},
+		{"", 0},
+		{"VNĐ", 0},
+		{"K", 0},
+		{"1,5", 15}, // Without a unit "," groups thousands
+		{"1,000,5 Triệu", 0},
+		{"100 USD", 0},
+		{"12.50 $", 0},
+		{"abc", 0},
+		{"100 K K", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != VND(tt.want) {
+			t.Errorf("ParseMoney(%q) = %v, %v, want %d VND", tt.in, got.Amount, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{VND(100_000), "100K VNĐ"},
+		{VND(1_000), "1K VNĐ"},
+		{VND(1_500), "1,5K VNĐ"},
+		{VND(1_000_000), "1 Triệu VNĐ"},
+		{VND(1_500_000), "1,5 Triệu VNĐ"},
+		{VND(1_250_000), "1,25 Triệu VNĐ"},
+		{VND(1_234_000), "1234K VNĐ"}, // Not exact with two decimals in Triệu
+		{VND(2_000_000_000), "2 Tỷ VNĐ"},
+		{VND(1_001), "1.001 VNĐ"},
+		{VND(999), "999 VNĐ"},
+		{VND(0), "0 VNĐ"},
+		{VND(-100_000), "-100.000 VNĐ"},
+		{VND(math.MaxInt64), "9.223.372.036.854.775.807 VNĐ"},
+		{VND(9_200_000_000_000_000_000), "9200000000 Tỷ VNĐ"}, // No overflow in the unit check
+		{Money{Amount: 1250, Currency: "USD"}, "12.50 USD"},
+		{Money{Amount: -5, Currency: "EUR"}, "-0.05 EUR"},
+		{Money{Amount: 42, Currency: "XYZ"}, "42 XYZ"}, // Unknown currencies have no minor units
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("%d %s: String() = %q, want %q", tt.money.Amount, tt.money.Currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{`{"amount": 1250, "currency": "usd"}`, Money{Amount: 1250, Currency: "USD"}, false},
+		{`{"amount": 100000}`, VND(100_000), false},
+		{`100000`, VND(100_000), false},
+		{`"1,5 Triệu VNĐ"`, VND(1_500_000), false},
+		{`""`, Money{}, false},
+		{`null`, Money{}, false},
+		{`"lots"`, Money{}, true},
+		{`1.5`, Money{}, true},
+		{`true`, Money{}, true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %+v, %v, want %+v (error %t)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	data, err := json.Marshal(VND(1_500_000))
+	if err != nil || string(data) != `{"amount":1500000,"currency":"VND","display":"1,5 Triệu VNĐ"}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+}
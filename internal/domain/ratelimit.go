@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTooManyRequests is the kind of every *RateLimitError
+var ErrTooManyRequests = errors.New("too many requests")
+
+// RateLimitError is returned when a caller exceeded a rate limit or its
+// account is locked; it tells them when to retry
+type RateLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Message
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrTooManyRequests
+}
+
+// RateLimit is a token bucket holding Burst tokens, refilled evenly over Per.
+// A zero Burst disables the limit.
+type RateLimit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// Disabled reports whether the limit lets everything through
+func (l RateLimit) Disabled() bool {
+	return l.Burst <= 0
+}
+
+type RateLimiter interface {
+	// Allow takes a token from the bucket of key. When the bucket is empty it
+	// returns false and how long until a token becomes available.
+	Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error)
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+// MaxImageSize is the largest image accepted for upload (5 MiB)
+const MaxImageSize = 5 << 20
+
+// ImageUpload is raw image data received from a client
+type ImageUpload struct {
+	ContentType string // As declared by the client; the bytes are sniffed anyway
+	Data        []byte
+}
+
+// ImageStorage persists uploaded images outside the database
+type ImageStorage interface {
+	// Save stores data under key and returns the URL it is served from
+	Save(ctx context.Context, key, contentType string, data []byte) (string, error)
+	Delete(ctx context.Context, key string) error
+}
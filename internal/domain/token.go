@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that was already
+// rotated or revoked is presented again
+var ErrRefreshTokenReused = Unauthorized("refresh token has already been used")
+
+// TokenPair is issued by AuthService.Login and AuthService.Refresh
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// RefreshToken is the stored form of a refresh token. Each rotation issues a
+// new token in the same family, so presenting an old one revokes the family.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	TokenHash string // SHA-256 of the token; the token itself is never stored
+	ExpiresAt time.Time
+	UsedAt    *time.Time // Set when rotated
+	RevokedAt *time.Time // Set on logout or reuse detection
+	CreatedAt time.Time
+}
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// UseRefreshToken marks the token as rotated; ErrRefreshTokenReused when
+	// it was already used or revoked
+	UseRefreshToken(ctx context.Context, id string) error
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserTokens(ctx context.Context, userID string) error
+	// RevokeAccessToken denylists an access token by jti until it expires
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// Transactor makes repository calls atomic: every call made with the ctx
+// passed to fn runs in one transaction, rolled back when fn returns an error
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -1,19 +1,80 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// Role controls which admin operations a user may perform
+type Role string
+
+const (
+	RoleUser   Role = "user"   // Signed in, no access to the admin routes
+	RoleViewer Role = "viewer" // Read-only access to configs and greetings
+	RoleEditor Role = "editor" // Viewer + create/update configs
+	RoleAdmin  Role = "admin"  // Editor + delete/activate configs and manage users
+)
+
+var roleRank = map[Role]int{
+	RoleUser:   0,
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Includes reports whether r grants at least the privileges of required.
+// RoleUser includes no admin role.
+func (r Role) Includes(required Role) bool {
+	return r.Valid() && required.Valid() && roleRank[r] >= roleRank[required]
+}
 
 type User struct {
 	ID       string `json:"id"`
 	Email    string `json:"email"`
-	Password string `json:"password,omitempty"` // omitempty allows hiding password in response
+	Password string `json:"-"` // bcrypt hash, never serialized
+	Role     Role   `json:"role"`
+
+	FailedLogins int        `json:"-"` // Consecutive failed logins, reset on success
+	LockedUntil  *time.Time `json:"-"` // Logins are refused until then
 }
 
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	UpdateRole(ctx context.Context, email string, role Role) error
+	RecordFailedLogin(ctx context.Context, id string) (int, error) // Returns the consecutive failures so far
+	LockUntil(ctx context.Context, id string, until time.Time) error
+	ResetFailedLogins(ctx context.Context, id string) error // Also lifts the lock
 }
 
 type AuthService interface {
 	Register(ctx context.Context, email, password string) (*User, error)
-	Login(ctx context.Context, email, password string) (*User, string, error) // Returns User and JWT token
+	Login(ctx context.Context, email, password string) (*User, *TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*User, *TokenPair, error) // Rotates the refresh token
+	Logout(ctx context.Context, accessToken, refreshToken string) error          // Revokes both tokens; refreshToken may be empty
+	ValidateToken(ctx context.Context, token string) (*User, error)              // Returns the User the token was issued to
+	SetRole(ctx context.Context, email string, role Role) error
+	RevokeSessions(ctx context.Context, email string) error // Revokes every refresh token of the user
+}
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// ContextWithUser returns a copy of ctx carrying the authenticated user
+func ContextWithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the authenticated user stored in ctx, if any
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey).(*User)
+	return user, ok && user != nil
 }
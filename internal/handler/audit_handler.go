@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+type AuditHandler struct {
+	auditService domain.AuditService
+}
+
+func NewAuditHandler(auditService domain.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// List returns a page of the audit log, newest first (admin endpoint).
+// Query parameters: target_type, target_id, actor (email), from, to
+// (RFC 3339), limit, cursor.
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	page, err := h.auditService.List(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseAuditFilter(query url.Values) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+		Actor:      query.Get("actor"),
+		Cursor:     query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return filter, domain.Invalid("limit", "limit must be an integer")
+		}
+		filter.Limit = n
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, domain.Invalid(name, name+" must be an RFC 3339 timestamp")
+			}
+			*dst = &t
+		}
+	}
+
+	return filter, nil
+}
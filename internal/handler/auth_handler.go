@@ -2,7 +2,6 @@ package handler
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strings"
 
@@ -26,14 +25,14 @@ type registerRequest struct {
 
 func validateEmail(email string) error {
 	if email == "" || !strings.Contains(email, "@") {
-		return errors.New("invalid email format")
+		return domain.Invalid("email", "invalid email format")
 	}
 	return nil
 }
 
 func validatePassword(password string) error {
 	if len(password) < 8 {
-		return errors.New("password must be at least 8 characters")
+		return domain.Invalid("password", "password must be at least 8 characters")
 	}
 	return nil
 }
@@ -46,17 +45,17 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := validateEmail(req.Email); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 	if err := validatePassword(req.Password); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
 	user, err := h.authService.Register(r.Context(), req.Email, req.Password)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -71,8 +70,9 @@ type loginRequest struct {
 }
 
 type loginResponse struct {
-	Token string       `json:"token"`
-	User  *domain.User `json:"user"`
+	Token string `json:"token"` // Same as access_token, kept for older clients
+	*domain.TokenPair
+	User *domain.User `json:"user"`
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -82,21 +82,114 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validateEmail(req.Email); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	// Only require presence here: the seeded admin account is "admin", not an email address
+	if req.Email == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "email and password are required")
+		return
+	}
+
+	user, tokens, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeTokens(w, user, tokens)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges a refresh token for a new token pair. Presenting a
+// refresh token twice revokes every token rotated from the same login.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "refresh_token is required")
 		return
 	}
 
-	user, token, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	user, tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken)
 	if err != nil {
-		// Distinction between 401 and 500 could be improved, but usually Login error is 401
-		writeError(w, http.StatusUnauthorized, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
+	writeTokens(w, user, tokens)
+}
+
+// Logout revokes the bearer access token and, when given, the refresh token
+// family it belongs to. It must run after RequireAuth.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	token, _ := bearerToken(r)
+	if err := h.authService.Logout(r.Context(), token, req.RefreshToken); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTokens(w http.ResponseWriter, user *domain.User, tokens *domain.TokenPair) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
 	json.NewEncoder(w).Encode(loginResponse{
-		Token: token,
-		User:  user,
+		Token:     tokens.AccessToken,
+		TokenPair: tokens,
+		User:      user,
 	})
 }
+
+type setRoleRequest struct {
+	Role domain.Role `json:"role"`
+}
+
+// SetRole changes the role of the user identified by the {email} path value (admin endpoint)
+func (h *AuthHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	email := r.PathValue("email")
+	if email == "" {
+		writeError(w, http.StatusBadRequest, "Invalid user email")
+		return
+	}
+
+	var req setRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.authService.SetRole(r.Context(), email, req.Role); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Role updated successfully"})
+}
+
+// RevokeSessions revokes every refresh token of the user identified by the
+// {email} path value, e.g. after a lost device (admin endpoint). Access
+// tokens already issued stay valid until they expire.
+func (h *AuthHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	email := r.PathValue("email")
+	if email == "" {
+		writeError(w, http.StatusBadRequest, "Invalid user email")
+		return
+	}
+
+	if err := h.authService.RevokeSessions(r.Context(), email); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
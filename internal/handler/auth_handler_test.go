@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/repository"
+	"my_backend/internal/service"
+)
+
+func newTestAuthService() domain.AuthService {
+	return service.NewAuthService(
+		repository.NewMemoryUserRepository(),
+		repository.NewMemoryTokenRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemoryTransactor(),
+		repository.NewMemoryRateLimiter(),
+		domain.RateLimit{}, // Unlimited
+		"0123456789abcdef0123456789abcdef",
+	)
+}
+
+// serveJSON sends body to h and returns the recorded response
+func serveJSON(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return rec
+}
+
+// login returns the access token of email
+func login(t *testing.T, h *AuthHandler, email, password string) string {
+	t.Helper()
+
+	rec := serveJSON(h.Login, `{"email":"`+email+`","password":"`+password+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil || res.AccessToken == "" {
+		t.Fatalf("login: no access token in %s", rec.Body)
+	}
+	return res.AccessToken
+}
+
+func TestRegisteredUserHasNoAdminAccess(t *testing.T) {
+	authService := newTestAuthService()
+	h := NewAuthHandler(authService)
+
+	rec := serveJSON(h.Register, `{"email":"new@example.com","password":"password123"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"role":"user"`) || strings.Contains(body, "password") {
+		t.Errorf("register response = %s, want role user and no password", body)
+	}
+
+	// Wrapped the way main protects the admin routes
+	admin := func(role domain.Role, token string) int {
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/lixi", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		RequireAuth(authService)(RequireRole(role)(ok)).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	token := login(t, h, "new@example.com", "password123")
+	for _, role := range []domain.Role{domain.RoleViewer, domain.RoleEditor, domain.RoleAdmin} {
+		if code := admin(role, token); code != http.StatusForbidden {
+			t.Errorf("fresh user on a %s route: status = %d, want 403", role, code)
+		}
+	}
+
+	// Once an admin grants a role, a new token carries it
+	if err := authService.SetRole(t.Context(), "new@example.com", domain.RoleViewer); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	token = login(t, h, "new@example.com", "password123")
+	if code := admin(domain.RoleViewer, token); code != http.StatusOK {
+		t.Errorf("viewer on a viewer route: status = %d, want 200", code)
+	}
+	if code := admin(domain.RoleEditor, token); code != http.StatusForbidden {
+		t.Errorf("viewer on an editor route: status = %d, want 403", code)
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"my_backend/internal/domain"
+)
+
+// setETag sends version as a strong entity tag
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// requireIfMatch returns the version a write is conditioned on. "*" matches
+// any version. It answers 428 when the header is missing and 412 when it
+// cannot match any version (weak or malformed tags), returning ok false.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (version int, ok bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		writeError(w, http.StatusPreconditionRequired, "If-Match header with the config's ETag is required")
+		return 0, false
+	}
+	if value == "*" {
+		return domain.AnyVersion, true
+	}
+
+	tag, quoted := strings.CutPrefix(value, `"`)
+	tag, closed := strings.CutSuffix(tag, `"`)
+	version, err := strconv.Atoi(tag)
+	if !quoted || !closed || err != nil || version < 1 {
+		writeServiceError(w, r, domain.ErrLixiConfigModified)
+		return 0, false
+	}
+	return version, true
+}
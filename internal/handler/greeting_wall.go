@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/logging"
+)
+
+// publicGreeting is the greeting shape shown on the public wall; it omits the
+// moderation fields
+type publicGreeting struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Amount    domain.Money `json:"amount"`
+	Message   string       `json:"message"`
+	Image     string       `json:"image"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type publicGreetingPage struct {
+	Greetings  []publicGreeting `json:"greetings"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Total      int              `json:"total"`
+}
+
+func toPublicGreeting(greeting *domain.LixiGreeting) publicGreeting {
+	return publicGreeting{
+		ID:        greeting.ID,
+		Name:      greeting.Name,
+		Amount:    greeting.Amount,
+		Message:   greeting.Message,
+		Image:     greeting.Image,
+		CreatedAt: greeting.CreatedAt,
+	}
+}
+
+// GetPublicGreetings returns a page of approved greetings, newest first
+// (public endpoint)
+func (h *LixiHandler) GetPublicGreetings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeServiceError(w, r, domain.Invalid("limit", "limit must be an integer"))
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.lixiService.GetPublicGreetings(r.Context(), query.Get("cursor"), limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := publicGreetingPage{
+		Greetings:  make([]publicGreeting, 0, len(page.Greetings)),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	for _, greeting := range page.Greetings {
+		resp.Greetings = append(resp.Greetings, toPublicGreeting(greeting))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// streamHeartbeat keeps idle streams from being cut by proxies and detects
+// clients that went away
+const streamHeartbeat = 15 * time.Second
+
+// StreamGreetings pushes greeting wall events as Server-Sent Events (public
+// endpoint): "greeting" with the greeting when one is approved, and
+// "greeting_removed" with {"id"} when one is taken down. Clients should load
+// GET /api/lixi/greetings first and again after reconnecting.
+func (h *LixiHandler) StreamGreetings(w http.ResponseWriter, r *http.Request) {
+	events, unsubscribe, err := h.lixiService.SubscribeGreetings()
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	defer unsubscribe()
+
+	// The server's WriteTimeout would otherwise cut the stream after a few
+	// seconds; the heartbeat takes over detecting dead connections
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		logging.FromContext(r.Context()).Warn("cannot clear write deadline for greeting stream", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+
+	// Ask EventSource clients to reconnect after 5s when the stream ends
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return // Shutting down
+			}
+			if err := writeGreetingEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeGreetingEvent(w http.ResponseWriter, event domain.GreetingEvent) error {
+	var data any = toPublicGreeting(event.Greeting)
+	if event.Type == domain.GreetingEventRemoved {
+		data = map[string]string{"id": event.Greeting.ID}
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", event.Type, event.Greeting.ID, payload)
+	return err
+}
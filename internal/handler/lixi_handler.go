@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"my_backend/internal/domain"
 )
@@ -18,23 +25,119 @@ func NewLixiHandler(lixiService domain.LixiService) *LixiHandler {
 	}
 }
 
-// GetActive returns the active lixi config (public endpoint)
+// publicLixiEnvelope is the envelope shape exposed to players; it omits the
+// probability weight so the client cannot predict or bias the draw
+type publicLixiEnvelope struct {
+	ID      int          `json:"id"`
+	Amount  domain.Money `json:"amount"`
+	Message string       `json:"message"`
+}
+
+type publicLixiConfig struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Envelopes []publicLixiEnvelope `json:"envelopes"`
+}
+
+func toPublicLixiConfig(config *domain.LixiConfig) publicLixiConfig {
+	envelopes := make([]publicLixiEnvelope, 0, len(config.Envelopes))
+	for _, env := range config.Envelopes {
+		envelopes = append(envelopes, publicLixiEnvelope{
+			ID:      env.ID,
+			Amount:  env.Amount,
+			Message: env.Message,
+		})
+	}
+
+	return publicLixiConfig{
+		ID:        config.ID,
+		Name:      config.Name,
+		Envelopes: envelopes,
+	}
+}
+
+// GetActive returns the active lixi config without rates (public endpoint)
 func (h *LixiHandler) GetActive(w http.ResponseWriter, r *http.Request) {
 	config, err := h.lixiService.GetActiveConfig(r.Context())
 	if err != nil {
-		writeError(w, http.StatusNotFound, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(config)
+	json.NewEncoder(w).Encode(toPublicLixiConfig(config))
+}
+
+type drawRequest struct {
+	Phone string `json:"phone"`
+	Email string `json:"email"`
+}
+
+type drawLimitResponse struct {
+	errorResponse
+	Previous []*domain.LixiDraw `json:"previous"`
+}
+
+// Draw opens one envelope of the active config server-side (public endpoint).
+// The participant is the authenticated user, else the phone/email in the
+// body, else the X-Device-Token header.
+func (h *LixiHandler) Draw(w http.ResponseWriter, r *http.Request) {
+	var req drawRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	participant := domain.Participant{
+		Phone:       req.Phone,
+		Email:       req.Email,
+		DeviceToken: r.Header.Get("X-Device-Token"),
+	}
+	if user, ok := domain.UserFromContext(r.Context()); ok {
+		participant.UserID = user.ID
+	}
+
+	draw, err := h.lixiService.Draw(r.Context(), participant)
+	if err != nil {
+		var limitErr *domain.DrawLimitError
+		if errors.As(err, &limitErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(drawLimitResponse{
+				errorResponse: errorResponse{Error: limitErr.Error(), Code: "conflict"},
+				Previous:      limitErr.Previous,
+			})
+			return
+		}
+
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(draw)
+}
+
+// IssueDeviceToken returns a signed anonymous token that identifies this
+// device as a draw participant (public endpoint)
+func (h *LixiHandler) IssueDeviceToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.lixiService.IssueDeviceToken(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"device_token": token})
 }
 
 // GetAll returns all lixi configs (admin endpoint)
 func (h *LixiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	configs, err := h.lixiService.GetAllConfigs(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -47,9 +150,26 @@ func (h *LixiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(configs)
 }
 
+// Get returns one lixi config with its version as the ETag (admin endpoint)
+func (h *LixiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	config, err := h.lixiService.GetConfig(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, config.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
 type createLixiRequest struct {
-	Name      string                 `json:"name"`
-	Envelopes []domain.LixiEnvelope `json:"envelopes"`
+	Name                string                `json:"name"`
+	Budget              *domain.Money         `json:"budget"`
+	DrawsPerParticipant *int                  `json:"draws_per_participant"`
+	StartsAt            *time.Time            `json:"starts_at"`
+	EndsAt              *time.Time            `json:"ends_at"`
+	Envelopes           []domain.LixiEnvelope `json:"envelopes"`
 }
 
 // Create creates a new lixi config (admin endpoint)
@@ -60,28 +180,60 @@ func (h *LixiHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	config, err := h.lixiService.CreateConfig(r.Context(), req.Name, req.Envelopes)
+	config, err := h.lixiService.CreateConfig(r.Context(), domain.LixiConfigInput{
+		Name:                req.Name,
+		Budget:              req.Budget,
+		DrawsPerParticipant: req.DrawsPerParticipant,
+		Schedule:            &domain.LixiSchedule{StartsAt: req.StartsAt, EndsAt: req.EndsAt},
+		Envelopes:           req.Envelopes,
+	})
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
+	setETag(w, config.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(config)
 }
 
+// optionalTime distinguishes an absent JSON field from an explicit null
+type optionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
+}
+
+// updateLixiRequest replaces starts_at and ends_at together: sending either
+// one sets the schedule (the other becoming open), omitting both keeps it
 type updateLixiRequest struct {
-	Name      string                 `json:"name"`
-	Envelopes []domain.LixiEnvelope `json:"envelopes"`
+	Name                string                `json:"name"`
+	Budget              *domain.Money         `json:"budget"`
+	DrawsPerParticipant *int                  `json:"draws_per_participant"`
+	StartsAt            optionalTime          `json:"starts_at"`
+	EndsAt              optionalTime          `json:"ends_at"`
+	Envelopes           []domain.LixiEnvelope `json:"envelopes"`
 }
 
 // Update updates a lixi config (admin endpoint)
 func (h *LixiHandler) Update(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from path: /api/admin/lixi/{id}
-	id := extractIDFromPath(r.URL.Path, "/api/admin/lixi/")
-	if id == "" {
-		writeError(w, http.StatusBadRequest, "Invalid config ID")
+	version, ok := requireIfMatch(w, r)
+	if !ok {
 		return
 	}
 
@@ -91,40 +243,37 @@ func (h *LixiHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	config, err := h.lixiService.UpdateConfig(r.Context(), id, req.Name, req.Envelopes)
+	input := domain.LixiConfigInput{
+		Name:                req.Name,
+		Budget:              req.Budget,
+		DrawsPerParticipant: req.DrawsPerParticipant,
+		Envelopes:           req.Envelopes,
+	}
+	if req.StartsAt.Set || req.EndsAt.Set {
+		input.Schedule = &domain.LixiSchedule{StartsAt: req.StartsAt.Value, EndsAt: req.EndsAt.Value}
+	}
+
+	config, err := h.lixiService.UpdateConfig(r.Context(), r.PathValue("id"), version, input)
 	if err != nil {
-		if err.Error() == "lixi config not found" {
-			writeError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
+	setETag(w, config.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(config)
 }
 
 // Delete deletes a lixi config (admin endpoint)
 func (h *LixiHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from path: /api/admin/lixi/{id}
-	id := extractIDFromPath(r.URL.Path, "/api/admin/lixi/")
-	if id == "" {
-		writeError(w, http.StatusBadRequest, "Invalid config ID")
+	version, ok := requireIfMatch(w, r)
+	if !ok {
 		return
 	}
 
-	err := h.lixiService.DeleteConfig(r.Context(), id)
+	err := h.lixiService.DeleteConfig(r.Context(), r.PathValue("id"), version)
 	if err != nil {
-		if err.Error() == "lixi config not found" {
-			writeError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		if err.Error() == "cannot delete active config" {
-			writeError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -133,47 +282,76 @@ func (h *LixiHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 // Activate sets a config as active (admin endpoint)
 func (h *LixiHandler) Activate(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from path: /api/admin/lixi/{id}/activate
-	path := r.URL.Path
-	path = strings.TrimSuffix(path, "/activate")
-	id := extractIDFromPath(path, "/api/admin/lixi/")
-	if id == "" {
-		writeError(w, http.StatusBadRequest, "Invalid config ID")
+	version, ok := requireIfMatch(w, r)
+	if !ok {
 		return
 	}
 
-	err := h.lixiService.SetActiveConfig(r.Context(), id)
+	config, err := h.lixiService.SetActiveConfig(r.Context(), r.PathValue("id"), version)
 	if err != nil {
-		if err.Error() == "lixi config not found" {
-			writeError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	setETag(w, config.Version)
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Config activated successfully"})
 }
 
+// Stats returns draw and greeting statistics of a config (admin endpoint)
+func (h *LixiHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.lixiService.GetConfigStats(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	// Figures change with every draw
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
 type submitGreetingRequest struct {
-	Name    string `json:"name"`
-	Amount  string `json:"amount"`
-	Message string `json:"message"`
-	Image   string `json:"image"`
+	Name    string       `json:"name"`
+	Amount  domain.Money `json:"amount"`
+	Message string       `json:"message"`
+	Image   string       `json:"image"` // data URL ("data:image/png;base64,...") or bare base64
 }
 
-// SubmitGreeting saves a greeting with an uploaded image (public endpoint)
+// maxGreetingBodySize leaves room for the form fields and base64 overhead
+const maxGreetingBodySize = domain.MaxImageSize*4/3 + 1<<20
+
+// SubmitGreeting saves a greeting with an uploaded image (public endpoint).
+// It accepts multipart/form-data with an "image" file part, or JSON with the
+// image inlined as a data URL for older clients.
 func (h *LixiHandler) SubmitGreeting(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxGreetingBodySize)
+
 	var req submitGreetingRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var image domain.ImageUpload
+	var err error
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		req, image, err = parseGreetingForm(r)
+	} else {
+		if err = json.NewDecoder(r.Body).Decode(&req); err == nil {
+			image, err = decodeDataURL(req.Image)
+		}
+	}
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
 		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	greeting, err := h.lixiService.SubmitGreeting(r.Context(), req.Name, req.Amount, req.Message, req.Image)
+	greeting, err := h.lixiService.SubmitGreeting(r.Context(), req.Name, req.Amount, req.Message, image)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -182,33 +360,180 @@ func (h *LixiHandler) SubmitGreeting(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(greeting)
 }
 
-// GetAllGreetings returns all greetings (admin endpoint)
+// parseGreetingForm reads the name/amount/message fields and the "image" file
+// of a multipart greeting submission. amount is either a display string
+// ("100K VNĐ") or, together with currency, an integer of minor units.
+func parseGreetingForm(r *http.Request) (submitGreetingRequest, domain.ImageUpload, error) {
+	var req submitGreetingRequest
+	var image domain.ImageUpload
+
+	if err := r.ParseMultipartForm(domain.MaxImageSize); err != nil {
+		return req, image, err
+	}
+
+	req.Name = r.FormValue("name")
+	req.Message = r.FormValue("message")
+
+	if amount := r.FormValue("amount"); amount != "" {
+		if currency := r.FormValue("currency"); currency != "" {
+			minor, err := strconv.ParseInt(amount, 10, 64)
+			if err != nil {
+				return req, image, err
+			}
+			req.Amount = domain.Money{Amount: minor, Currency: strings.ToUpper(currency)}
+		} else {
+			money, err := domain.ParseMoney(amount)
+			if err != nil {
+				return req, image, err
+			}
+			req.Amount = money
+		}
+	}
+
+	file, header, err := r.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return req, image, nil // reported as "image is required" by the service
+	}
+	if err != nil {
+		return req, image, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, domain.MaxImageSize+1))
+	if err != nil {
+		return req, image, err
+	}
+
+	image = domain.ImageUpload{
+		ContentType: header.Header.Get("Content-Type"),
+		Data:        data,
+	}
+	return req, image, nil
+}
+
+// decodeDataURL decodes "data:image/png;base64,<data>" or bare base64
+func decodeDataURL(value string) (domain.ImageUpload, error) {
+	if value == "" {
+		return domain.ImageUpload{}, nil
+	}
+
+	contentType := ""
+	payload := value
+	if rest, ok := strings.CutPrefix(value, "data:"); ok {
+		meta, data, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(meta, ";base64") {
+			return domain.ImageUpload{}, errors.New("image must be a base64 data URL")
+		}
+		contentType = strings.TrimSuffix(meta, ";base64")
+		payload = data
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return domain.ImageUpload{}, err
+	}
+
+	return domain.ImageUpload{ContentType: contentType, Data: data}, nil
+}
+
+// GetAllGreetings returns a page of greetings (admin endpoint).
+// Query parameters: limit, cursor, name, currency, min_amount, max_amount
+// (minor units), from, to (RFC 3339), sort (created_at|amount, "-" prefix
+// for descending; default -created_at).
 func (h *LixiHandler) GetAllGreetings(w http.ResponseWriter, r *http.Request) {
-	greetings, err := h.lixiService.GetAllGreetings(r.Context())
+	filter, err := parseGreetingFilter(r.URL.Query())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
-	if greetings == nil {
-		greetings = []*domain.LixiGreeting{}
+	page, err := h.lixiService.GetAllGreetings(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	if page.Greetings == nil {
+		page.Greetings = []*domain.LixiGreeting{}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(greetings)
+	json.NewEncoder(w).Encode(page)
+}
+
+type moderateGreetingsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// ModerateGreetings returns a handler setting the given status on the
+// greetings listed in the body (admin endpoint). It answers with the IDs
+// that were updated and those that do not exist.
+func (h *LixiHandler) ModerateGreetings(status domain.GreetingStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := domain.UserFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		var req moderateGreetingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		result, err := h.lixiService.ModerateGreetings(r.Context(), req.IDs, status, user.Email)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
 }
 
-// Helper function to extract ID from path
-func extractIDFromPath(path, prefix string) string {
-	if !strings.HasPrefix(path, prefix) {
-		return ""
+func parseGreetingFilter(query url.Values) (domain.GreetingFilter, error) {
+	filter := domain.GreetingFilter{
+		Name:     strings.TrimSpace(query.Get("name")),
+		Status:   domain.GreetingStatus(query.Get("status")),
+		Currency: strings.ToUpper(query.Get("currency")),
+		Cursor:   query.Get("cursor"),
 	}
-	id := strings.TrimPrefix(path, prefix)
-	// Remove trailing slash if present
-	id = strings.TrimSuffix(id, "/")
-	// Handle paths like "123/activate" - get only the ID part
-	if idx := strings.Index(id, "/"); idx != -1 {
-		id = id[:idx]
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return filter, domain.Invalid("limit", "limit must be an integer")
+		}
+		filter.Limit = n
 	}
-	return id
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "-" + domain.GreetingSortCreatedAt
+	}
+	filter.SortBy, filter.Ascending = strings.TrimPrefix(sort, "-"), !strings.HasPrefix(sort, "-")
+
+	for name, dst := range map[string]**int64{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, domain.Invalid(name, name+" must be an integer amount in minor units")
+			}
+			*dst = &n
+		}
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, domain.Invalid(name, name+" must be an RFC 3339 timestamp")
+			}
+			*dst = &t
+		}
+	}
+
+	return filter, nil
 }
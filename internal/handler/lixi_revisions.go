@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"my_backend/internal/domain"
+)
+
+// GetRevisions lists the revisions of a config, newest first (admin endpoint)
+func (h *LixiHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	revisions, err := h.lixiService.GetConfigRevisions(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// DiffRevisions compares two revisions of a config (admin endpoint). Query
+// parameters: from, to (revision numbers; to defaults to the latest and
+// from to the one before it).
+func (h *LixiHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var revisions [2]int
+	for i, name := range []string{"from", "to"} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				writeServiceError(w, r, domain.Invalid(name, name+" must be a revision number"))
+				return
+			}
+			revisions[i] = n
+		}
+	}
+
+	diff, err := h.lixiService.DiffConfigRevisions(r.Context(), r.PathValue("id"), revisions[0], revisions[1])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// RestoreRevision saves a past revision as the current state of its config
+// (admin endpoint)
+func (h *LixiHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	revision, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil || revision < 1 {
+		writeError(w, http.StatusBadRequest, "Invalid revision")
+		return
+	}
+
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	config, err := h.lixiService.RestoreConfigRevision(r.Context(), r.PathValue("id"), version, revision)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, config.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
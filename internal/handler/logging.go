@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/logging"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestID propagates the caller's X-Request-ID (or assigns a new one),
+// echoes it in the response and tags the request logger with it. The ID and
+// client IP are also stored as the domain.RequestInfo of the request.
+func RequestID(logger *slog.Logger, trustProxyHeaders bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(requestIDHeader, id)
+			ctx := logging.WithLogger(r.Context(), logger.With("request_id", id))
+			ctx = domain.ContextWithRequestInfo(ctx, domain.RequestInfo{ID: id, IP: clientIP(r, trustProxyHeaders)})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID accepts short IDs made of URL-safe characters, so callers
+// cannot inject arbitrary content into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessLogEntry collects what inner handlers learn about a request (such as
+// the authenticated user) for the access log written after it completes
+type accessLogEntry struct {
+	userID string
+}
+
+type accessLogContextKey struct{}
+
+// setAccessLogUser records the authenticated user in the access log entry
+// and tags the request logger with it
+func setAccessLogUser(ctx context.Context, user *domain.User) context.Context {
+	if entry, ok := ctx.Value(accessLogContextKey{}).(*accessLogEntry); ok {
+		entry.userID = user.ID
+	}
+	return logging.WithLogger(ctx, logging.FromContext(ctx).With("user_id", user.ID))
+}
+
+// AccessLog writes one structured log line per request with its route
+// pattern, status, size, latency and user. It must run after RequestID.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessLogEntry{}
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		r = r.WithContext(context.WithValue(r.Context(), accessLogContextKey{}, entry))
+		next.ServeHTTP(recorder, r)
+
+		// The ServeMux sets r.Pattern on the request it was handed
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", r.Pattern,
+			"status", recorder.Status(),
+			"bytes", recorder.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"remote_addr", r.RemoteAddr,
+		}
+		if entry.userID != "" {
+			attrs = append(attrs, "user_id", entry.userID)
+		}
+
+		level := slog.LevelInfo
+		if recorder.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(r.Context()).Log(r.Context(), level, "http request", attrs...)
+	})
+}
+
+// Recover turns a panicking handler into a logged 500 response
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder, ok := w.(*statusRecorder)
+		if !ok {
+			recorder = &statusRecorder{ResponseWriter: w}
+		}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v) // Deliberate abort, let net/http handle it
+			}
+
+			logging.FromContext(r.Context()).Error("panic serving request",
+				"panic", v,
+				"stack", string(debug.Stack()),
+			)
+
+			if !recorder.wroteHeader {
+				writeError(recorder, http.StatusInternalServerError, "internal server error")
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// statusRecorder captures the status code and body size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Status is the response status, 200 when the handler wrote nothing
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
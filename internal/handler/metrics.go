@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"my_backend/internal/metrics"
+)
+
+// Metrics counts requests and observes their latency per route pattern. It
+// must run inside AccessLog, whose status recorder it reuses.
+func Metrics(r *metrics.Registry) func(http.Handler) http.Handler {
+	requests := r.NewCounterVec("http_requests_total",
+		"HTTP requests served, per route pattern and status code", "route", "code")
+	duration := r.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency, per route pattern", metrics.DefaultBuckets, "route")
+	inFlight := r.NewGaugeVec("http_requests_in_flight",
+		"HTTP requests currently being served")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			recorder, ok := w.(*statusRecorder)
+			if !ok {
+				recorder = &statusRecorder{ResponseWriter: w}
+			}
+
+			start := time.Now()
+			inFlight.Add(1)
+			defer func() {
+				inFlight.Add(-1)
+
+				// Label by pattern rather than path to keep the number of
+				// series bounded; unmatched paths share one label
+				route := req.Pattern
+				if route == "" {
+					route = "unmatched"
+				}
+				requests.Inc(route, strconv.Itoa(recorder.Status()))
+				duration.Observe(time.Since(start).Seconds(), route)
+			}()
+
+			next.ServeHTTP(recorder, req)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"my_backend/internal/domain"
+)
+
+// RequireAuth rejects requests that do not carry a valid bearer token and
+// stores the authenticated user in the request context
+func RequireAuth(authService domain.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				writeError(w, http.StatusUnauthorized, "missing or malformed Authorization header")
+				return
+			}
+
+			user, err := authService.ValidateToken(r.Context(), token)
+			if err != nil {
+				writeServiceError(w, r, err)
+				return
+			}
+
+			ctx := setAccessLogUser(r.Context(), user)
+			next.ServeHTTP(w, r.WithContext(domain.ContextWithUser(ctx, user)))
+		})
+	}
+}
+
+// OptionalAuth stores the authenticated user in the request context when a
+// bearer token is present; anonymous requests pass through unchanged
+func OptionalAuth(authService domain.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			RequireAuth(authService)(next).ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole rejects requests whose authenticated user does not hold at least
+// the given role. It must run after RequireAuth.
+func RequireRole(role domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := domain.UserFromContext(r.Context())
+			if !ok {
+				writeError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			if !user.Role.Includes(role) {
+				writeError(w, http.StatusForbidden, "insufficient permissions")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireStaticToken rejects requests whose bearer token is not token, for
+// machine endpoints such as /metrics. An empty token disables the check.
+func RequireStaticToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := bearerToken(r)
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"my_backend/internal/domain"
+)
+
+func TestRequireRole(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		user     domain.Role
+		required domain.Role
+		want     int
+	}{
+		{domain.RoleUser, domain.RoleViewer, http.StatusForbidden},
+		{domain.RoleUser, domain.RoleEditor, http.StatusForbidden},
+		{domain.RoleUser, domain.RoleAdmin, http.StatusForbidden},
+		{"", domain.RoleViewer, http.StatusForbidden},
+		{"superuser", domain.RoleViewer, http.StatusForbidden},
+		{domain.RoleViewer, domain.RoleViewer, http.StatusOK},
+		{domain.RoleViewer, domain.RoleEditor, http.StatusForbidden},
+		{domain.RoleEditor, domain.RoleViewer, http.StatusOK},
+		{domain.RoleEditor, domain.RoleAdmin, http.StatusForbidden},
+		{domain.RoleAdmin, domain.RoleAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		ctx := domain.ContextWithUser(t.Context(), &domain.User{ID: "1", Email: "a@example.com", Role: tt.user})
+		rec := httptest.NewRecorder()
+		RequireRole(tt.required)(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/lixi", nil).WithContext(ctx))
+
+		if rec.Code != tt.want {
+			t.Errorf("role %q on a %s route: status = %d, want %d", tt.user, tt.required, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestRequireRoleWithoutUser(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RequireRole(domain.RoleViewer)(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/lixi", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
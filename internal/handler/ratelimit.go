@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/logging"
+)
+
+// RateLimitByIP rejects requests with 429 once the client IP used up its
+// limit for the named endpoint. When the limiter itself fails the request is
+// let through, so a database hiccup does not take the endpoint down.
+func RateLimitByIP(limiter domain.RateLimiter, trustProxyHeaders bool, name string, limit domain.RateLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit.Disabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := name + ":" + clientIP(r, trustProxyHeaders)
+
+			allowed, retryAfter, err := limiter.Allow(r.Context(), key, limit)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("rate limiter failed, allowing request", "error", err)
+			} else if !allowed {
+				writeRateLimited(w, &domain.RateLimitError{Message: "too many requests, try again later", RetryAfter: retryAfter})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the address of the caller. Behind a load balancer the
+// connection comes from the proxy, so the last X-Forwarded-For entry (the one
+// appended by our proxy, which clients cannot forge) is used instead.
+func clientIP(r *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			hops := strings.Split(values[len(values)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
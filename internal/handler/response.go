@@ -2,15 +2,81 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/logging"
 )
 
 type errorResponse struct {
-	Error string `json:"error"`
+	Error  string            `json:"error"`
+	Code   string            `json:"code"`             // e.g. "not_found", "conflict"
+	Fields map[string]string `json:"fields,omitempty"` // Validation message per request field
+}
+
+// kindStatus maps domain error kinds to HTTP status codes
+var kindStatus = []struct {
+	kind   error
+	status int
+}{
+	{domain.ErrValidation, http.StatusBadRequest},
+	{domain.ErrUnauthorized, http.StatusUnauthorized},
+	{domain.ErrForbidden, http.StatusForbidden},
+	{domain.ErrNotFound, http.StatusNotFound},
+	{domain.ErrConflict, http.StatusConflict},
+	{domain.ErrPrecondition, http.StatusPreconditionFailed},
+	{domain.ErrUnavailable, http.StatusServiceUnavailable},
 }
 
 func writeError(w http.ResponseWriter, status int, message string) {
+	writeErrorResponse(w, status, errorResponse{Error: message})
+}
+
+// writeServiceError responds with the status matching the kind of a
+// *domain.Error, or 429 for a *domain.RateLimitError. Any other error is
+// logged and hidden behind a 500, since it may carry database or
+// infrastructure details.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var limitErr *domain.RateLimitError
+	if errors.As(err, &limitErr) {
+		writeRateLimited(w, limitErr)
+		return
+	}
+
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		logging.FromContext(r.Context()).Error("internal error", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	status := http.StatusInternalServerError
+	for _, ks := range kindStatus {
+		if errors.Is(domainErr.Kind, ks.kind) {
+			status = ks.status
+			break
+		}
+	}
+
+	writeErrorResponse(w, status, errorResponse{Error: domainErr.Message, Fields: domainErr.Fields})
+}
+
+// writeRateLimited responds with 429 and a Retry-After header in whole seconds
+func writeRateLimited(w http.ResponseWriter, err *domain.RateLimitError) {
+	seconds := int(math.Ceil(err.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	writeError(w, http.StatusTooManyRequests, err.Message)
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, body errorResponse) {
+	// "Not Found" -> "not_found"
+	body.Code = strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(errorResponse{Error: message})
+	json.NewEncoder(w).Encode(body)
 }
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"io/fs"
+	"net/http"
+)
+
+// ServeFiles serves the files under dir by exact name. Directories answer
+// 404 instead of a listing, so stored images can't be enumerated.
+func ServeFiles(dir string) http.Handler {
+	return http.FileServer(filesOnly{http.Dir(dir)})
+}
+
+// filesOnly hides the directories of a file system
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+	return file, nil
+}
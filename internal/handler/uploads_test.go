@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServeFiles(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	if err := os.MkdirAll(filepath.Join(dir, "greetings", "2025"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "greetings", "2025", "a.png"), []byte("png data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /uploads/", http.StripPrefix("/uploads/", ServeFiles(dir)))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/uploads/greetings/2025/a.png", http.StatusOK},
+		{"/uploads/", http.StatusNotFound},
+		{"/uploads/greetings/", http.StatusNotFound},
+		{"/uploads/greetings", http.StatusNotFound},
+		{"/uploads/greetings/2025/", http.StatusNotFound},
+		{"/uploads/greetings/2025/missing.png", http.StatusNotFound},
+		{"/uploads/../secret.txt", http.StatusNotFound},
+		{"/secret.txt", http.StatusNotFound},
+		{"/uploads/%2e%2e/secret.txt", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		// The mux redirects unclean paths to their cleaned form, which is
+		// checked on its own
+		if rec.Code != tt.want && !(tt.want == http.StatusNotFound && rec.Code == http.StatusTemporaryRedirect) {
+			t.Errorf("GET %s: status = %d, want %d (body %q)", tt.path, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
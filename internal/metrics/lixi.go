@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+// lixiService records business events of the wrapped LixiService
+type lixiService struct {
+	domain.LixiService
+	draws      *CounterVec
+	rejections *CounterVec
+	greetings  *CounterVec
+}
+
+// InstrumentLixiService wraps service so that draws, rejected draws and
+// submitted greetings are counted in r
+func InstrumentLixiService(r *Registry, service domain.LixiService) domain.LixiService {
+	return &lixiService{
+		LixiService: service,
+		draws: r.NewCounterVec("lixi_draws_total",
+			"Envelopes drawn, per config and envelope", "config_id", "envelope_id"),
+		rejections: r.NewCounterVec("lixi_draw_rejections_total",
+			"Draws that did not hand out an envelope, per reason", "reason"),
+		greetings: r.NewCounterVec("lixi_greetings_submitted_total",
+			"Greetings submitted"),
+	}
+}
+
+func (s *lixiService) Draw(ctx context.Context, participant domain.Participant) (*domain.LixiDraw, error) {
+	draw, err := s.LixiService.Draw(ctx, participant)
+	if err != nil {
+		s.rejections.Inc(drawRejectionReason(err))
+		return nil, err
+	}
+
+	s.draws.Inc(draw.ConfigID, strconv.Itoa(draw.EnvelopeID))
+	return draw, nil
+}
+
+func drawRejectionReason(err error) string {
+	var limitErr *domain.DrawLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		return "limit_reached"
+	case errors.Is(err, domain.ErrNoActiveLixiConfig):
+		return "no_active_config"
+	case errors.Is(err, domain.ErrConflict):
+		return "sold_out"
+	case errors.Is(err, domain.ErrValidation), errors.Is(err, domain.ErrUnauthorized):
+		return "invalid_participant"
+	default:
+		return "error"
+	}
+}
+
+func (s *lixiService) SubmitGreeting(ctx context.Context, name string, amount domain.Money, message string, image domain.ImageUpload) (*domain.LixiGreeting, error) {
+	greeting, err := s.LixiService.SubmitGreeting(ctx, name, amount, message, image)
+	if err == nil {
+		s.greetings.Inc()
+	}
+	return greeting, err
+}
+
+// RegisterActiveLixiConfig exposes the ID of the active lixi config as an
+// info-style gauge, looked up on every scrape
+func RegisterActiveLixiConfig(r *Registry, lixiRepo domain.LixiRepository) {
+	r.NewGaugeFunc("lixi_active_config", "Set to 1 for the currently active lixi config", []string{"config_id"}, func() []Sample {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		config, err := lixiRepo.GetActive(ctx)
+		if err != nil {
+			return nil
+		}
+		return []Sample{{Values: []string{config.ID}, Value: 1}}
+	})
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrape renders the registry through its HTTP handler
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", ct)
+	}
+	body, _ := io.ReadAll(res.Body)
+	return string(body)
+}
+
+// assertLines checks that want appears in body as consecutive lines
+func assertLines(t *testing.T, body string, want ...string) {
+	t.Helper()
+	if !strings.Contains(body, strings.Join(want, "\n")+"\n") {
+		t.Errorf("output is missing\n%s\ngot\n%s", strings.Join(want, "\n"), body)
+	}
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("http_requests_total", "Requests served.", "method", "status")
+	r.NewCounterVec("errors_total", "Errors.")
+	inFlight := r.NewGaugeVec("in_flight", "Requests in flight.")
+
+	requests.Inc("GET", "200")
+	requests.Add(2, "GET", "200")
+	requests.Inc("POST", "201")
+	inFlight.Add(3)
+	inFlight.Add(-1)
+
+	body := scrape(t, r)
+	assertLines(t, body,
+		"# HELP http_requests_total Requests served.",
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",status="200"} 3`,
+		`http_requests_total{method="POST",status="201"} 1`,
+	)
+	// Label-less metrics are reported before their first update
+	assertLines(t, body,
+		"# HELP errors_total Errors.",
+		"# TYPE errors_total counter",
+		"errors_total 0",
+	)
+	assertLines(t, body,
+		"# TYPE in_flight gauge",
+		"in_flight 2",
+	)
+
+	// Metrics are rendered in registration order
+	if strings.Index(body, "http_requests_total") > strings.Index(body, "in_flight") {
+		t.Error("metrics are not in registration order")
+	}
+}
+
+func TestLabelAndHelpEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("escaped_total", "Help with \\ and\nnewline.", "path")
+	c.Inc(`C:\dir "quoted"` + "\nnext")
+
+	assertLines(t, scrape(t, r),
+		`# HELP escaped_total Help with \\ and\nnewline.`,
+		"# TYPE escaped_total counter",
+		`escaped_total{path="C:\\dir \"quoted\"\nnext"} 1`,
+	)
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 0.5, 1}, "route")
+
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2} {
+		h.Observe(v, "/api")
+	}
+
+	assertLines(t, scrape(t, r),
+		"# HELP latency_seconds Latency.",
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{route="/api",le="0.1"} 2`, // Bounds are inclusive
+		`latency_seconds_bucket{route="/api",le="0.5"} 3`,
+		`latency_seconds_bucket{route="/api",le="1"} 4`,
+		`latency_seconds_bucket{route="/api",le="+Inf"} 5`,
+		`latency_seconds_sum{route="/api"} 3.15`,
+		`latency_seconds_count{route="/api"} 5`,
+	)
+}
+
+func TestFuncCollectors(t *testing.T) {
+	r := NewRegistry()
+	open := 1.0
+	r.NewGaugeFunc("pool_open", "Open connections.", []string{"pool"}, func() []Sample {
+		return []Sample{{Values: []string{"main"}, Value: open}}
+	})
+	r.NewCounterFunc("pool_acquired_total", "Acquired connections.", nil, func() []Sample {
+		return []Sample{{Value: 42}}
+	})
+
+	open = 4 // Collected at scrape time, not registration
+	body := scrape(t, r)
+	assertLines(t, body,
+		"# TYPE pool_open gauge",
+		`pool_open{pool="main"} 4`,
+	)
+	assertLines(t, body,
+		"# TYPE pool_acquired_total counter",
+		"pool_acquired_total 42",
+	)
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	c := NewRegistry().NewCounterVec("mismatch_total", "Mismatch.", "a", "b")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with the wrong number of label values did not panic")
+		}
+	}()
+	c.Inc("only-one")
+}
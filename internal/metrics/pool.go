@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RegisterPoolStats exposes the connection pool statistics, read on every scrape
+func RegisterPoolStats(r *Registry, pool *pgxpool.Pool) {
+	gauge := func(name, help string, value func(*pgxpool.Stat) float64) {
+		r.NewGaugeFunc(name, help, nil, func() []Sample {
+			return []Sample{{Value: value(pool.Stat())}}
+		})
+	}
+	counter := func(name, help string, value func(*pgxpool.Stat) float64) {
+		r.NewCounterFunc(name, help, nil, func() []Sample {
+			return []Sample{{Value: value(pool.Stat())}}
+		})
+	}
+
+	gauge("db_pool_acquired_connections", "Connections currently in use",
+		func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) })
+	gauge("db_pool_idle_connections", "Idle connections in the pool",
+		func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) })
+	gauge("db_pool_total_connections", "Open connections, including ones being established",
+		func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) })
+	gauge("db_pool_max_connections", "Maximum size of the pool",
+		func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) })
+	counter("db_pool_acquires_total", "Connections acquired from the pool",
+		func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) })
+	counter("db_pool_empty_acquires_total", "Acquires that had to wait because the pool was empty",
+		func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) })
+	counter("db_pool_canceled_acquires_total", "Acquires canceled by their context",
+		func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) })
+	counter("db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections",
+		func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() })
+	counter("db_pool_empty_acquire_wait_seconds_total", "Total time spent waiting for a connection while the pool was empty",
+		func(s *pgxpool.Stat) float64 { return s.EmptyAcquireWaitTime().Seconds() })
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+// RegisterRuntime exposes basic process metrics
+func RegisterRuntime(r *Registry) {
+	start := time.Now()
+
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist", nil, func() []Sample {
+		return []Sample{{Value: float64(runtime.NumGoroutine())}}
+	})
+	r.NewGaugeFunc("go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects", nil, func() []Sample {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return []Sample{{Value: float64(m.HeapAlloc)}}
+	})
+	r.NewGaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch", nil, func() []Sample {
+		return []Sample{{Value: float64(start.Unix())}}
+	})
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"my_backend/internal/database"
+	"my_backend/internal/domain"
+)
+
+type postgresAuditRepository struct {
+	db database.DBTX
+}
+
+// NewPostgresAuditRepository creates an AuditRepository backed by the
+// append-only audit_log table
+func NewPostgresAuditRepository(db database.DBTX) domain.AuditRepository {
+	return &postgresAuditRepository{db: db}
+}
+
+func (r *postgresAuditRepository) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, actor, action, target_type, target_id, changes, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	var id int64
+	err = database.Conn(ctx, r.db).QueryRow(ctx, query, entry.ActorID, entry.Actor, entry.Action, entry.TargetType, entry.TargetID, changes, entry.RequestID, entry.IP).Scan(&id, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	entry.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+func (r *postgresAuditRepository) List(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	var conditions []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = "+arg(filter.TargetType))
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = "+arg(filter.TargetID))
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = "+arg(filter.Actor))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.To))
+	}
+	if filter.Cursor != "" {
+		before, err := decodeAuditCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "id < "+arg(before))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	query := fmt.Sprintf(`
+		SELECT id, actor_id, actor, action, target_type, target_id, changes, request_id, ip, created_at
+		FROM audit_log
+		%s
+		ORDER BY id DESC
+		LIMIT %s
+	`, where, arg(filter.Limit+1))
+
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	page := &domain.AuditPage{}
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var id int64
+		var changes []byte
+
+		if err := rows.Scan(&id, &entry.ActorID, &entry.Actor, &entry.Action, &entry.TargetType, &entry.TargetID, &changes, &entry.RequestID, &entry.IP, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit changes: %w", err)
+		}
+
+		entry.ID = strconv.FormatInt(id, 10)
+		page.Entries = append(page.Entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	if len(page.Entries) > filter.Limit {
+		page.Entries = page.Entries[:filter.Limit]
+		page.NextCursor = page.Entries[filter.Limit-1].ID
+	}
+
+	return page, nil
+}
+
+// decodeAuditCursor returns the ID the next page starts below; cursors are
+// the ID of the last entry of the previous page
+func decodeAuditCursor(cursor string) (int64, error) {
+	id, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, domain.Invalid("cursor", "invalid cursor")
+	}
+	return id, nil
+}
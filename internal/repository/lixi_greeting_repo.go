@@ -2,27 +2,37 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"my_backend/internal/database"
 	"my_backend/internal/domain"
+
+	"github.com/jackc/pgx/v5"
 )
 
-type postgresLixiGreetingRepository struct{}
+type postgresLixiGreetingRepository struct {
+	db database.DBTX
+}
 
-func NewPostgresLixiGreetingRepository() domain.LixiGreetingRepository {
-	return &postgresLixiGreetingRepository{}
+func NewPostgresLixiGreetingRepository(db database.DBTX) domain.LixiGreetingRepository {
+	return &postgresLixiGreetingRepository{db: db}
 }
 
 func (r *postgresLixiGreetingRepository) Create(ctx context.Context, greeting *domain.LixiGreeting) error {
 	query := `
-		INSERT INTO lixi_greetings (name, amount, message, image)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO lixi_greetings (name, amount_minor, currency, message, image, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at
 	`
 
 	var id int64
-	err := database.DB.QueryRow(ctx, query, greeting.Name, greeting.Amount, greeting.Message, greeting.Image).Scan(&id, &greeting.CreatedAt)
+	err := database.Conn(ctx, r.db).QueryRow(ctx, query, greeting.Name, greeting.Amount.Amount, greeting.Amount.Currency, greeting.Message, greeting.Image, greeting.Status).Scan(&id, &greeting.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create lixi greeting: %w", err)
 	}
@@ -31,31 +41,272 @@ func (r *postgresLixiGreetingRepository) Create(ctx context.Context, greeting *d
 	return nil
 }
 
-func (r *postgresLixiGreetingRepository) GetAll(ctx context.Context) ([]*domain.LixiGreeting, error) {
-	query := `
-		SELECT id, name, amount, message, image, created_at
+// greetingColumns are the columns read by scanGreeting, in order
+const greetingColumns = "id, name, amount_minor, currency, message, image, status, moderated_by, moderated_at, created_at"
+
+// scanGreeting reads a row of greetingColumns and also returns the numeric id
+func scanGreeting(row pgx.Row) (*domain.LixiGreeting, int64, error) {
+	var greeting domain.LixiGreeting
+	var id int64
+	var moderatedBy *string
+
+	if err := row.Scan(&id, &greeting.Name, &greeting.Amount.Amount, &greeting.Amount.Currency, &greeting.Message, &greeting.Image, &greeting.Status, &moderatedBy, &greeting.ModeratedAt, &greeting.CreatedAt); err != nil {
+		return nil, 0, err
+	}
+
+	greeting.ID = fmt.Sprintf("%d", id)
+	if moderatedBy != nil {
+		greeting.ModeratedBy = *moderatedBy
+	}
+	return &greeting, id, nil
+}
+
+func (r *postgresLixiGreetingRepository) GetByID(ctx context.Context, id string) (*domain.LixiGreeting, error) {
+	greetingID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, domain.ErrGreetingNotFound
+	}
+
+	query := "SELECT " + greetingColumns + " FROM lixi_greetings WHERE id = $1"
+	greeting, _, err := scanGreeting(database.Conn(ctx, r.db).QueryRow(ctx, query, greetingID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrGreetingNotFound
+		}
+		return nil, fmt.Errorf("failed to get lixi greeting: %w", err)
+	}
+
+	return greeting, nil
+}
+
+func (r *postgresLixiGreetingRepository) GetAll(ctx context.Context, filter domain.GreetingFilter) (*domain.GreetingPage, error) {
+	var conditions []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Name != "" {
+		conditions = append(conditions, "name ILIKE "+arg("%"+escapeLike(filter.Name)+"%"))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+arg(filter.Status))
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "currency = "+arg(filter.Currency))
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "amount_minor >= "+arg(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "amount_minor <= "+arg(*filter.MaxAmount))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.To))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &domain.GreetingPage{}
+	if err := database.Conn(ctx, r.db).QueryRow(ctx, "SELECT COUNT(*) FROM lixi_greetings "+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count lixi greetings: %w", err)
+	}
+
+	sortColumn := "created_at"
+	if filter.SortBy == domain.GreetingSortAmount {
+		sortColumn = "amount_minor"
+	}
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeGreetingCursor(filter.Cursor, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		var key any = cursor.Key
+		if sortColumn == "created_at" {
+			key = time.UnixMicro(cursor.Key)
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", sortColumn, comparison, arg(key), arg(cursor.ID)))
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM lixi_greetings
-		ORDER BY created_at DESC
-	`
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT %s
+	`, greetingColumns, where, sortColumn, direction, direction, arg(filter.Limit+1))
 
-	rows, err := database.DB.Query(ctx, query)
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all lixi greetings: %w", err)
 	}
 	defer rows.Close()
 
-	var greetings []*domain.LixiGreeting
+	var ids []int64
 	for rows.Next() {
-		var greeting domain.LixiGreeting
-		var id int64
-
-		if err := rows.Scan(&id, &greeting.Name, &greeting.Amount, &greeting.Message, &greeting.Image, &greeting.CreatedAt); err != nil {
+		greeting, id, err := scanGreeting(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan lixi greeting: %w", err)
 		}
 
-		greeting.ID = fmt.Sprintf("%d", id)
-		greetings = append(greetings, &greeting)
+		page.Greetings = append(page.Greetings, greeting)
+		ids = append(ids, id)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get all lixi greetings: %w", err)
+	}
+
+	if len(page.Greetings) > filter.Limit {
+		page.Greetings = page.Greetings[:filter.Limit]
+		last := page.Greetings[filter.Limit-1]
+		page.NextCursor = encodeGreetingCursor(last, ids[filter.Limit-1], filter)
+	}
+
+	return page, nil
+}
+
+func (r *postgresLixiGreetingRepository) SetStatus(ctx context.Context, ids []string, status domain.GreetingStatus, moderator string) ([]*domain.LixiGreeting, error) {
+	query := `
+		UPDATE lixi_greetings
+		SET status = $1, moderated_by = $2, moderated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($3)
+		RETURNING ` + greetingColumns
+
+	// IDs that are not numbers cannot exist
+	greetingIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+			greetingIDs = append(greetingIDs, n)
+		}
+	}
+
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, status, moderator, greetingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update lixi greeting status: %w", err)
+	}
+
+	updated, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.LixiGreeting, error) {
+		greeting, _, err := scanGreeting(row)
+		return greeting, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update lixi greeting status: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (r *postgresLixiGreetingRepository) CountPerHour(ctx context.Context, from, to time.Time) ([]domain.HourlyCount, error) {
+	// Hours are truncated in UTC, whatever the session time zone
+	query := `
+		SELECT date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS hour, COUNT(*)
+		FROM lixi_greetings
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY hour
+		ORDER BY hour
+	`
+
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lixi greetings per hour: %w", err)
+	}
+
+	counts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.HourlyCount, error) {
+		var count domain.HourlyCount
+		err := row.Scan(&count.Hour, &count.Count)
+		return count, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lixi greetings per hour: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *postgresLixiGreetingRepository) TopMessages(ctx context.Context, from, to time.Time, limit int) ([]domain.MessageCount, error) {
+	query := `
+		SELECT MIN(btrim(message)), COUNT(*) AS sent
+		FROM lixi_greetings
+		WHERE status = $1 AND created_at >= $2 AND created_at < $3 AND btrim(message) <> ''
+		GROUP BY lower(btrim(message))
+		ORDER BY sent DESC, MIN(btrim(message))
+		LIMIT $4
+	`
+
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, domain.GreetingApproved, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top lixi greeting messages: %w", err)
+	}
+
+	messages, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.MessageCount, error) {
+		var message domain.MessageCount
+		err := row.Scan(&message.Message, &message.Count)
+		return message, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top lixi greeting messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// greetingCursor is the keyset position of the last greeting on a page: the
+// sort key (unix microseconds or minor units) and the id breaking ties
+type greetingCursor struct {
+	Sort      string `json:"s"`
+	Ascending bool   `json:"a,omitempty"`
+	Key       int64  `json:"k"`
+	ID        int64  `json:"i"`
+}
+
+var errInvalidGreetingCursor = domain.Invalid("cursor", "invalid cursor")
+
+func encodeGreetingCursor(last *domain.LixiGreeting, id int64, filter domain.GreetingFilter) string {
+	cursor := greetingCursor{Sort: filter.SortBy, Ascending: filter.Ascending, ID: id}
+	if filter.SortBy == domain.GreetingSortAmount {
+		cursor.Key = last.Amount.Amount
+	} else {
+		cursor.Key = last.CreatedAt.UnixMicro()
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeGreetingCursor rejects cursors issued for a different ordering
+func decodeGreetingCursor(value string, filter domain.GreetingFilter) (greetingCursor, error) {
+	var cursor greetingCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errInvalidGreetingCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, errInvalidGreetingCursor
+	}
+	if cursor.Sort != filter.SortBy || cursor.Ascending != filter.Ascending {
+		return cursor, errInvalidGreetingCursor
+	}
+
+	return cursor, nil
+}
 
-	return greetings, nil
+// escapeLike makes s match literally inside a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
@@ -5,15 +5,46 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"my_backend/internal/database"
 	"my_backend/internal/domain"
+	"my_backend/internal/logging"
+
+	"github.com/jackc/pgx/v5"
 )
 
-type postgresLixiRepository struct{}
+type postgresLixiRepository struct {
+	db database.DBTX
+}
+
+func NewPostgresLixiRepository(db database.DBTX) domain.LixiRepository {
+	return &postgresLixiRepository{db: db}
+}
+
+// lixiConfigColumns is the column list scanned by scanLixiConfig
+const lixiConfigColumns = `id, name, envelopes, is_active, budget, spent, currency, draws_per_participant, starts_at, ends_at, version, created_at`
+
+// scanLixiConfig scans a row selected with lixiConfigColumns
+func scanLixiConfig(row pgx.Row) (*domain.LixiConfig, error) {
+	var config domain.LixiConfig
+	var id, budget, spent int64
+	var currency string
+	var envelopesJSON []byte
+
+	if err := row.Scan(&id, &config.Name, &envelopesJSON, &config.IsActive, &budget, &spent, &currency, &config.DrawsPerParticipant, &config.StartsAt, &config.EndsAt, &config.Version, &config.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	config.Budget = domain.Money{Amount: budget, Currency: currency}
+	config.Spent = domain.Money{Amount: spent, Currency: currency}
+
+	if err := json.Unmarshal(envelopesJSON, &config.Envelopes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal envelopes: %w", err)
+	}
 
-func NewPostgresLixiRepository() domain.LixiRepository {
-	return &postgresLixiRepository{}
+	config.ID = fmt.Sprintf("%d", id)
+	return &config, nil
 }
 
 func (r *postgresLixiRepository) Create(ctx context.Context, config *domain.LixiConfig) error {
@@ -23,13 +54,13 @@ func (r *postgresLixiRepository) Create(ctx context.Context, config *domain.Lixi
 	}
 
 	query := `
-		INSERT INTO lixi_configs (name, envelopes, is_active)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO lixi_configs (name, envelopes, is_active, budget, currency, draws_per_participant, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, version, created_at
 	`
 
 	var id int64
-	err = database.DB.QueryRow(ctx, query, config.Name, envelopesJSON, config.IsActive).Scan(&id, &config.CreatedAt)
+	err = database.Conn(ctx, r.db).QueryRow(ctx, query, config.Name, envelopesJSON, config.IsActive, config.Budget.Amount, config.Budget.Currency, config.DrawsPerParticipant, config.StartsAt, config.EndsAt).Scan(&id, &config.Version, &config.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create lixi config: %w", err)
 	}
@@ -40,67 +71,49 @@ func (r *postgresLixiRepository) Create(ctx context.Context, config *domain.Lixi
 
 func (r *postgresLixiRepository) GetActive(ctx context.Context) (*domain.LixiConfig, error) {
 	query := `
-		SELECT id, name, envelopes, is_active, created_at
+		SELECT ` + lixiConfigColumns + `
 		FROM lixi_configs
 		WHERE is_active = TRUE
 		LIMIT 1
 	`
 
-	var config domain.LixiConfig
-	var id int64
-	var envelopesJSON []byte
-
-	err := database.DB.QueryRow(ctx, query).Scan(&id, &config.Name, &envelopesJSON, &config.IsActive, &config.CreatedAt)
+	config, err := scanLixiConfig(database.Conn(ctx, r.db).QueryRow(ctx, query))
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return nil, errors.New("no active lixi config found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNoActiveLixiConfig
 		}
 		return nil, fmt.Errorf("failed to get active lixi config: %w", err)
 	}
 
-	if err := json.Unmarshal(envelopesJSON, &config.Envelopes); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal envelopes: %w", err)
-	}
-
-	config.ID = fmt.Sprintf("%d", id)
-	return &config, nil
+	return config, nil
 }
 
 func (r *postgresLixiRepository) GetByID(ctx context.Context, id string) (*domain.LixiConfig, error) {
 	query := `
-		SELECT id, name, envelopes, is_active, created_at
+		SELECT ` + lixiConfigColumns + `
 		FROM lixi_configs
 		WHERE id = $1
 	`
 
-	var config domain.LixiConfig
-	var dbID int64
-	var envelopesJSON []byte
-
-	err := database.DB.QueryRow(ctx, query, id).Scan(&dbID, &config.Name, &envelopesJSON, &config.IsActive, &config.CreatedAt)
+	config, err := scanLixiConfig(database.Conn(ctx, r.db).QueryRow(ctx, query, id))
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return nil, errors.New("lixi config not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrLixiConfigNotFound
 		}
 		return nil, fmt.Errorf("failed to get lixi config: %w", err)
 	}
 
-	if err := json.Unmarshal(envelopesJSON, &config.Envelopes); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal envelopes: %w", err)
-	}
-
-	config.ID = fmt.Sprintf("%d", dbID)
-	return &config, nil
+	return config, nil
 }
 
 func (r *postgresLixiRepository) GetAll(ctx context.Context) ([]*domain.LixiConfig, error) {
 	query := `
-		SELECT id, name, envelopes, is_active, created_at
+		SELECT ` + lixiConfigColumns + `
 		FROM lixi_configs
 		ORDER BY created_at DESC
 	`
 
-	rows, err := database.DB.Query(ctx, query)
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all lixi configs: %w", err)
 	}
@@ -108,20 +121,14 @@ func (r *postgresLixiRepository) GetAll(ctx context.Context) ([]*domain.LixiConf
 
 	var configs []*domain.LixiConfig
 	for rows.Next() {
-		var config domain.LixiConfig
-		var id int64
-		var envelopesJSON []byte
-
-		if err := rows.Scan(&id, &config.Name, &envelopesJSON, &config.IsActive, &config.CreatedAt); err != nil {
+		config, err := scanLixiConfig(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan lixi config: %w", err)
 		}
-
-		if err := json.Unmarshal(envelopesJSON, &config.Envelopes); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal envelopes: %w", err)
-		}
-
-		config.ID = fmt.Sprintf("%d", id)
-		configs = append(configs, &config)
+		configs = append(configs, config)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get all lixi configs: %w", err)
 	}
 
 	return configs, nil
@@ -133,61 +140,96 @@ func (r *postgresLixiRepository) Update(ctx context.Context, config *domain.Lixi
 		return fmt.Errorf("failed to marshal envelopes: %w", err)
 	}
 
+	// The drawn counters are owned by RecordDraw, so carry them over from the
+	// stored row instead of trusting the (possibly stale) values in config
 	query := `
 		UPDATE lixi_configs
-		SET name = $1, envelopes = $2
-		WHERE id = $3
+		SET name = $1,
+			budget = $2,
+			currency = $5,
+			draws_per_participant = $6,
+			starts_at = $7,
+			ends_at = $8,
+			version = version + 1,
+			envelopes = (
+				SELECT jsonb_agg(
+					jsonb_set(e.value, '{drawn}', COALESCE(lixi_configs.envelopes -> (e.ordinality::int - 1) -> 'drawn', '0'::jsonb))
+					ORDER BY e.ordinality
+				)
+				FROM jsonb_array_elements($3::jsonb) WITH ORDINALITY AS e(value, ordinality)
+			)
+		WHERE id = $4 AND version = $9
+		RETURNING envelopes, spent, version
 	`
 
-	result, err := database.DB.Exec(ctx, query, config.Name, envelopesJSON, config.ID)
+	var storedJSON []byte
+	var spent int64
+	err = database.Conn(ctx, r.db).QueryRow(ctx, query, config.Name, config.Budget.Amount, envelopesJSON, config.ID, config.Budget.Currency, config.DrawsPerParticipant, config.StartsAt, config.EndsAt, config.Version).Scan(&storedJSON, &spent, &config.Version)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r.missingOrModified(ctx, config.ID)
+		}
 		return fmt.Errorf("failed to update lixi config: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return errors.New("lixi config not found")
+	if err := json.Unmarshal(storedJSON, &config.Envelopes); err != nil {
+		return fmt.Errorf("failed to unmarshal envelopes: %w", err)
 	}
 
+	config.Spent = domain.Money{Amount: spent, Currency: config.Budget.Currency}
 	return nil
 }
 
-func (r *postgresLixiRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM lixi_configs WHERE id = $1`
+// missingOrModified tells why a versioned write to config id matched no row
+func (r *postgresLixiRepository) missingOrModified(ctx context.Context, id string) error {
+	var exists bool
+	if err := database.Conn(ctx, r.db).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM lixi_configs WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check lixi config: %w", err)
+	}
+
+	if !exists {
+		return domain.ErrLixiConfigNotFound
+	}
+	return domain.ErrLixiConfigModified
+}
 
-	result, err := database.DB.Exec(ctx, query, id)
+func (r *postgresLixiRepository) Delete(ctx context.Context, id string, version int) error {
+	query := `DELETE FROM lixi_configs WHERE id = $1 AND ($2 = 0 OR version = $2)`
+
+	result, err := database.Conn(ctx, r.db).Exec(ctx, query, id, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete lixi config: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return errors.New("lixi config not found")
+		return r.missingOrModified(ctx, id)
 	}
 
 	return nil
 }
 
-func (r *postgresLixiRepository) SetActive(ctx context.Context, id string) error {
+func (r *postgresLixiRepository) SetActive(ctx context.Context, id string, version int) error {
 	// Use transaction to ensure atomicity
-	tx, err := database.DB.Begin(ctx)
+	tx, err := database.Conn(ctx, r.db).Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Deactivate all configs
-	_, err = tx.Exec(ctx, `UPDATE lixi_configs SET is_active = FALSE WHERE is_active = TRUE`)
+	_, err = tx.Exec(ctx, `UPDATE lixi_configs SET is_active = FALSE, version = version + 1 WHERE is_active = TRUE AND id <> $1`, id)
 	if err != nil {
 		return fmt.Errorf("failed to deactivate configs: %w", err)
 	}
 
 	// Activate the specified config
-	result, err := tx.Exec(ctx, `UPDATE lixi_configs SET is_active = TRUE WHERE id = $1`, id)
+	result, err := tx.Exec(ctx, `UPDATE lixi_configs SET is_active = TRUE, version = version + 1 WHERE id = $1 AND ($2 = 0 OR version = $2)`, id, version)
 	if err != nil {
 		return fmt.Errorf("failed to activate config: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return errors.New("lixi config not found")
+		return r.missingOrModified(ctx, id)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -196,3 +238,164 @@ func (r *postgresLixiRepository) SetActive(ctx context.Context, id string) error
 
 	return nil
 }
+
+func (r *postgresLixiRepository) Deactivate(ctx context.Context, id string) error {
+	result, err := database.Conn(ctx, r.db).Exec(ctx, `UPDATE lixi_configs SET is_active = FALSE, version = version + 1 WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate config: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrLixiConfigNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresLixiRepository) RecordDraw(ctx context.Context, draw *domain.LixiDraw) error {
+	tx, err := database.Conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Count the draw against the participant; the conflict update takes a row
+	// lock, so concurrent draws by the same participant are serialized
+	participantQuery := `
+		INSERT INTO lixi_participants (config_id, participant_key, draws)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (config_id, participant_key) DO UPDATE
+		SET draws = lixi_participants.draws + 1, last_draw_at = CURRENT_TIMESTAMP
+		WHERE lixi_participants.draws < (
+			SELECT draws_per_participant FROM lixi_configs WHERE id = lixi_participants.config_id
+		)
+	`
+
+	result, err := tx.Exec(ctx, participantQuery, draw.ConfigID, draw.ParticipantKey)
+	if err != nil {
+		return fmt.Errorf("failed to record lixi participant: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrDrawLimitReached
+	}
+
+	// Consume one unit of stock and the payout from the budget in a single
+	// conditional UPDATE so concurrent draws can never oversell
+	index := draw.EnvelopeID - 1
+	consumeQuery := `
+		UPDATE lixi_configs
+		SET envelopes = jsonb_set(
+				envelopes,
+				$3::text[],
+				to_jsonb(COALESCE((envelopes -> $2::int ->> 'drawn')::int, 0) + 1)
+			),
+			spent = spent + $4
+		WHERE id = $1
+			AND is_active = TRUE
+			AND currency = $5
+			AND jsonb_array_length(envelopes) > $2::int
+			AND (budget = 0 OR spent + $4 <= budget)
+			AND (
+				COALESCE((envelopes -> $2::int ->> 'quantity')::int, 0) = 0
+				OR COALESCE((envelopes -> $2::int ->> 'drawn')::int, 0) < (envelopes -> $2::int ->> 'quantity')::int
+			)
+	`
+
+	path := []string{strconv.Itoa(index), "drawn"}
+	result, err = tx.Exec(ctx, consumeQuery, draw.ConfigID, index, path, draw.Amount.Amount, draw.Amount.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to consume envelope stock: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		logging.FromContext(ctx).Debug("lixi envelope unavailable", "config_id", draw.ConfigID, "envelope_id", draw.EnvelopeID)
+		return domain.ErrEnvelopeUnavailable
+	}
+
+	insertQuery := `
+		INSERT INTO lixi_draws (config_id, envelope_id, amount_minor, currency, message, participant_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	var id int64
+	err = tx.QueryRow(ctx, insertQuery, draw.ConfigID, draw.EnvelopeID, draw.Amount.Amount, draw.Amount.Currency, draw.Message, draw.ParticipantKey).Scan(&id, &draw.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record lixi draw: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	draw.ID = fmt.Sprintf("%d", id)
+	return nil
+}
+
+func (r *postgresLixiRepository) GetParticipantDraws(ctx context.Context, configID, participantKey string) ([]*domain.LixiDraw, error) {
+	query := `
+		SELECT id, config_id, envelope_id, amount_minor, currency, message, participant_key, created_at
+		FROM lixi_draws
+		WHERE config_id = $1 AND participant_key = $2
+		ORDER BY created_at
+	`
+
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, configID, participantKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant draws: %w", err)
+	}
+	defer rows.Close()
+
+	var draws []*domain.LixiDraw
+	for rows.Next() {
+		var draw domain.LixiDraw
+		var id, dbConfigID int64
+
+		if err := rows.Scan(&id, &dbConfigID, &draw.EnvelopeID, &draw.Amount.Amount, &draw.Amount.Currency, &draw.Message, &draw.ParticipantKey, &draw.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lixi draw: %w", err)
+		}
+
+		draw.ID = fmt.Sprintf("%d", id)
+		draw.ConfigID = fmt.Sprintf("%d", dbConfigID)
+		draws = append(draws, &draw)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get participant draws: %w", err)
+	}
+
+	return draws, nil
+}
+
+func (r *postgresLixiRepository) GetDrawStats(ctx context.Context, configID string) (*domain.DrawStats, error) {
+	// Both aggregates are served by the (config_id, ...) indexes of their tables
+	query := `
+		SELECT envelope_id, COUNT(*), COALESCE(SUM(amount_minor), 0)
+		FROM lixi_draws
+		WHERE config_id = $1
+		GROUP BY envelope_id
+		ORDER BY envelope_id
+	`
+
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lixi draw stats: %w", err)
+	}
+
+	envelopes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.EnvelopeDrawCount, error) {
+		var count domain.EnvelopeDrawCount
+		err := row.Scan(&count.EnvelopeID, &count.Draws, &count.PaidOut)
+		return count, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lixi draw stats: %w", err)
+	}
+
+	stats := &domain.DrawStats{Envelopes: envelopes}
+	err = database.Conn(ctx, r.db).QueryRow(ctx, `SELECT COUNT(*) FROM lixi_participants WHERE config_id = $1`, configID).Scan(&stats.Participants)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lixi participants: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"my_backend/internal/database"
+	"my_backend/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type postgresLixiRevisionRepository struct {
+	db database.DBTX
+}
+
+func NewPostgresLixiRevisionRepository(db database.DBTX) domain.LixiRevisionRepository {
+	return &postgresLixiRevisionRepository{db: db}
+}
+
+// lixiRevisionColumns is the column list scanned by scanLixiRevision
+const lixiRevisionColumns = `config_id, revision, name, envelopes, budget, currency, draws_per_participant, starts_at, ends_at, restored_from, created_by, created_at`
+
+func scanLixiRevision(row pgx.Row) (*domain.LixiConfigRevision, error) {
+	var revision domain.LixiConfigRevision
+	var configID int64
+	var envelopesJSON []byte
+
+	if err := row.Scan(&configID, &revision.Revision, &revision.Name, &envelopesJSON, &revision.Budget.Amount, &revision.Budget.Currency, &revision.DrawsPerParticipant, &revision.StartsAt, &revision.EndsAt, &revision.RestoredFrom, &revision.CreatedBy, &revision.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(envelopesJSON, &revision.Envelopes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal envelopes: %w", err)
+	}
+
+	revision.ConfigID = fmt.Sprintf("%d", configID)
+	return &revision, nil
+}
+
+func (r *postgresLixiRevisionRepository) Create(ctx context.Context, revision *domain.LixiConfigRevision) error {
+	envelopesJSON, err := json.Marshal(revision.Envelopes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal envelopes: %w", err)
+	}
+
+	// Saves of a config are serialized by the row lock its UPDATE takes, so
+	// the next number cannot be taken twice
+	query := `
+		INSERT INTO lixi_config_revisions (config_id, revision, name, envelopes, budget, currency, draws_per_participant, starts_at, ends_at, restored_from, created_by)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		FROM lixi_config_revisions
+		WHERE config_id = $1
+		RETURNING revision, created_at
+	`
+
+	err = database.Conn(ctx, r.db).QueryRow(ctx, query, revision.ConfigID, revision.Name, envelopesJSON, revision.Budget.Amount, revision.Budget.Currency, revision.DrawsPerParticipant, revision.StartsAt, revision.EndsAt, revision.RestoredFrom, revision.CreatedBy).Scan(&revision.Revision, &revision.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create lixi config revision: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresLixiRevisionRepository) List(ctx context.Context, configID string) ([]*domain.LixiConfigRevision, error) {
+	query := `
+		SELECT ` + lixiRevisionColumns + `
+		FROM lixi_config_revisions
+		WHERE config_id = $1
+		ORDER BY revision DESC
+	`
+
+	rows, err := database.Conn(ctx, r.db).Query(ctx, query, configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lixi config revisions: %w", err)
+	}
+
+	revisions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.LixiConfigRevision, error) {
+		return scanLixiRevision(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lixi config revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (r *postgresLixiRevisionRepository) Get(ctx context.Context, configID string, revision int) (*domain.LixiConfigRevision, error) {
+	query := `
+		SELECT ` + lixiRevisionColumns + `
+		FROM lixi_config_revisions
+		WHERE config_id = $1 AND revision = $2
+	`
+
+	rev, err := scanLixiRevision(database.Conn(ctx, r.db).QueryRow(ctx, query, configID, revision))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to get lixi config revision: %w", err)
+	}
+
+	return rev, nil
+}
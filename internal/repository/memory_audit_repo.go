@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+type memoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []domain.AuditEntry // In ID order
+	nextID  int64
+}
+
+// NewMemoryAuditRepository creates an AuditRepository that keeps entries in
+// process memory, for running without Postgres
+func NewMemoryAuditRepository() domain.AuditRepository {
+	return &memoryAuditRepository{}
+}
+
+func (r *memoryAuditRepository) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	entry.ID = strconv.FormatInt(r.nextID, 10)
+	entry.CreatedAt = time.Now()
+
+	stored := *entry
+	stored.Changes = maps.Clone(entry.Changes)
+	r.entries = append(r.entries, stored)
+	return nil
+}
+
+func (r *memoryAuditRepository) List(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	var before int64
+	if filter.Cursor != "" {
+		var err error
+		if before, err = decodeAuditCursor(filter.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page := &domain.AuditPage{}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		e := r.entries[i]
+		if before != 0 && int64(i+1) >= before {
+			continue // IDs are positions + 1
+		}
+
+		switch {
+		case filter.TargetType != "" && e.TargetType != filter.TargetType,
+			filter.TargetID != "" && e.TargetID != filter.TargetID,
+			filter.Actor != "" && e.Actor != filter.Actor,
+			filter.From != nil && e.CreatedAt.Before(*filter.From),
+			filter.To != nil && !e.CreatedAt.Before(*filter.To):
+			continue
+		}
+
+		if len(page.Entries) == filter.Limit {
+			page.NextCursor = page.Entries[len(page.Entries)-1].ID
+			break
+		}
+		e.Changes = maps.Clone(e.Changes)
+		page.Entries = append(page.Entries, &e)
+	}
+
+	return page, nil
+}
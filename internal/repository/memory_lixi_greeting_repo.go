@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+type memoryGreeting struct {
+	id       int64
+	greeting domain.LixiGreeting
+}
+
+type memoryLixiGreetingRepository struct {
+	mu        sync.RWMutex
+	greetings []memoryGreeting
+	nextID    int64
+}
+
+// NewMemoryLixiGreetingRepository creates a LixiGreetingRepository that keeps
+// greetings in process memory, for running without Postgres
+func NewMemoryLixiGreetingRepository() domain.LixiGreetingRepository {
+	return &memoryLixiGreetingRepository{}
+}
+
+func (r *memoryLixiGreetingRepository) Create(ctx context.Context, greeting *domain.LixiGreeting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	greeting.ID = fmt.Sprintf("%d", r.nextID)
+	greeting.CreatedAt = time.Now()
+
+	r.greetings = append(r.greetings, memoryGreeting{id: r.nextID, greeting: *greeting})
+	return nil
+}
+
+func (r *memoryLixiGreetingRepository) GetAll(ctx context.Context, filter domain.GreetingFilter) (*domain.GreetingPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// sortKey mirrors the keyset columns of the Postgres repository
+	sortKey := func(g *domain.LixiGreeting) int64 {
+		if filter.SortBy == domain.GreetingSortAmount {
+			return g.Amount.Amount
+		}
+		return g.CreatedAt.UnixMicro()
+	}
+	// before reports whether (key, id) comes before (otherKey, otherID) in the requested order
+	before := func(key, id, otherKey, otherID int64) bool {
+		if key != otherKey {
+			return (key < otherKey) == filter.Ascending
+		}
+		return id != otherID && (id < otherID) == filter.Ascending
+	}
+
+	var matches []memoryGreeting
+	for _, g := range r.greetings {
+		if matchesGreetingFilter(&g.greeting, filter) {
+			matches = append(matches, g)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return before(sortKey(&matches[i].greeting), matches[i].id, sortKey(&matches[j].greeting), matches[j].id)
+	})
+
+	page := &domain.GreetingPage{Total: len(matches)}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeGreetingCursor(filter.Cursor, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		start := sort.Search(len(matches), func(i int) bool {
+			return before(cursor.Key, cursor.ID, sortKey(&matches[i].greeting), matches[i].id)
+		})
+		matches = matches[start:]
+	}
+
+	for i, g := range matches {
+		if i == filter.Limit {
+			last := page.Greetings[len(page.Greetings)-1]
+			page.NextCursor = encodeGreetingCursor(last, matches[i-1].id, filter)
+			break
+		}
+		greeting := g.greeting
+		page.Greetings = append(page.Greetings, &greeting)
+	}
+
+	return page, nil
+}
+
+func (r *memoryLixiGreetingRepository) GetByID(ctx context.Context, id string) (*domain.LixiGreeting, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, g := range r.greetings {
+		if g.greeting.ID == id {
+			greeting := g.greeting
+			return &greeting, nil
+		}
+	}
+	return nil, domain.ErrGreetingNotFound
+}
+
+func (r *memoryLixiGreetingRepository) SetStatus(ctx context.Context, ids []string, status domain.GreetingStatus, moderator string) ([]*domain.LixiGreeting, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	now := time.Now()
+	var updated []*domain.LixiGreeting
+	for i := range r.greetings {
+		g := &r.greetings[i].greeting
+		if wanted[g.ID] {
+			g.Status, g.ModeratedBy, g.ModeratedAt = status, moderator, &now
+			greeting := *g
+			updated = append(updated, &greeting)
+		}
+	}
+	return updated, nil
+}
+
+func (r *memoryLixiGreetingRepository) CountPerHour(ctx context.Context, from, to time.Time) ([]domain.HourlyCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	perHour := make(map[time.Time]int)
+	for _, g := range r.greetings {
+		if !g.greeting.CreatedAt.Before(from) && g.greeting.CreatedAt.Before(to) {
+			perHour[g.greeting.CreatedAt.UTC().Truncate(time.Hour)]++
+		}
+	}
+
+	counts := make([]domain.HourlyCount, 0, len(perHour))
+	for hour, count := range perHour {
+		counts = append(counts, domain.HourlyCount{Hour: hour, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Hour.Before(counts[j].Hour)
+	})
+	return counts, nil
+}
+
+func (r *memoryLixiGreetingRepository) TopMessages(ctx context.Context, from, to time.Time, limit int) ([]domain.MessageCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Messages are grouped case-insensitively and reported with the
+	// smallest spelling, as in the Postgres repository
+	byKey := make(map[string]*domain.MessageCount)
+	for _, g := range r.greetings {
+		message := strings.TrimSpace(g.greeting.Message)
+		if g.greeting.Status != domain.GreetingApproved || message == "" ||
+			g.greeting.CreatedAt.Before(from) || !g.greeting.CreatedAt.Before(to) {
+			continue
+		}
+
+		key := strings.ToLower(message)
+		count, ok := byKey[key]
+		if !ok {
+			count = &domain.MessageCount{Message: message}
+			byKey[key] = count
+		}
+		count.Count++
+		count.Message = min(count.Message, message)
+	}
+
+	messages := make([]domain.MessageCount, 0, len(byKey))
+	for _, count := range byKey {
+		messages = append(messages, *count)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].Count != messages[j].Count {
+			return messages[i].Count > messages[j].Count
+		}
+		return messages[i].Message < messages[j].Message
+	})
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func matchesGreetingFilter(g *domain.LixiGreeting, filter domain.GreetingFilter) bool {
+	switch {
+	case filter.Name != "" && !strings.Contains(strings.ToLower(g.Name), strings.ToLower(filter.Name)),
+		filter.Status != "" && g.Status != filter.Status,
+		filter.Currency != "" && g.Amount.Currency != filter.Currency,
+		filter.MinAmount != nil && g.Amount.Amount < *filter.MinAmount,
+		filter.MaxAmount != nil && g.Amount.Amount > *filter.MaxAmount,
+		filter.From != nil && g.CreatedAt.Before(*filter.From),
+		filter.To != nil && !g.CreatedAt.Before(*filter.To):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/logging"
+)
+
+type memoryLixiRepository struct {
+	mu           sync.RWMutex
+	configs      map[string]*domain.LixiConfig
+	draws        []*domain.LixiDraw
+	participants map[string]int // "<config id>/<participant key>" -> draws
+	nextID       int64
+	nextDrawID   int64
+}
+
+// NewMemoryLixiRepository creates a LixiRepository that keeps everything in
+// process memory, for running without Postgres
+func NewMemoryLixiRepository() domain.LixiRepository {
+	return &memoryLixiRepository{
+		configs:      make(map[string]*domain.LixiConfig),
+		participants: make(map[string]int),
+	}
+}
+
+// copyConfig returns a deep copy so callers never share state with the store
+func copyConfig(config *domain.LixiConfig) *domain.LixiConfig {
+	c := *config
+	c.Envelopes = append([]domain.LixiEnvelope(nil), config.Envelopes...)
+	return &c
+}
+
+func (r *memoryLixiRepository) Create(ctx context.Context, config *domain.LixiConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	config.ID = fmt.Sprintf("%d", r.nextID)
+	config.CreatedAt = time.Now()
+	config.Spent = domain.Money{Currency: config.Budget.Currency}
+	config.Version = 1
+
+	r.configs[config.ID] = copyConfig(config)
+	return nil
+}
+
+func (r *memoryLixiRepository) GetActive(ctx context.Context) (*domain.LixiConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, config := range r.configs {
+		if config.IsActive {
+			return copyConfig(config), nil
+		}
+	}
+	return nil, domain.ErrNoActiveLixiConfig
+}
+
+func (r *memoryLixiRepository) GetByID(ctx context.Context, id string) (*domain.LixiConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	config, exists := r.configs[id]
+	if !exists {
+		return nil, domain.ErrLixiConfigNotFound
+	}
+	return copyConfig(config), nil
+}
+
+func (r *memoryLixiRepository) GetAll(ctx context.Context) ([]*domain.LixiConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	configs := make([]*domain.LixiConfig, 0, len(r.configs))
+	for _, config := range r.configs {
+		configs = append(configs, copyConfig(config))
+	}
+
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].CreatedAt.After(configs[j].CreatedAt)
+	})
+	return configs, nil
+}
+
+func (r *memoryLixiRepository) Update(ctx context.Context, config *domain.LixiConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.configs[config.ID]
+	if !exists {
+		return domain.ErrLixiConfigNotFound
+	}
+	if stored.Version != config.Version {
+		return domain.ErrLixiConfigModified
+	}
+
+	// The drawn counters are owned by RecordDraw, as in the Postgres repository
+	envelopes := append([]domain.LixiEnvelope(nil), config.Envelopes...)
+	for i := range envelopes {
+		envelopes[i].Drawn = 0
+		if i < len(stored.Envelopes) {
+			envelopes[i].Drawn = stored.Envelopes[i].Drawn
+		}
+	}
+
+	stored.Name = config.Name
+	stored.Budget = config.Budget
+	stored.Spent.Currency = config.Budget.Currency
+	stored.DrawsPerParticipant = config.DrawsPerParticipant
+	stored.StartsAt, stored.EndsAt = config.StartsAt, config.EndsAt
+	stored.Envelopes = envelopes
+	stored.Version++
+
+	config.Version = stored.Version
+	config.Envelopes = append(config.Envelopes[:0], envelopes...)
+	config.Spent = stored.Spent
+	return nil
+}
+
+// checkVersion returns the stored config id if it is at version
+func (r *memoryLixiRepository) checkVersion(id string, version int) (*domain.LixiConfig, error) {
+	config, exists := r.configs[id]
+	if !exists {
+		return nil, domain.ErrLixiConfigNotFound
+	}
+	if version != domain.AnyVersion && config.Version != version {
+		return nil, domain.ErrLixiConfigModified
+	}
+	return config, nil
+}
+
+func (r *memoryLixiRepository) Delete(ctx context.Context, id string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.checkVersion(id, version); err != nil {
+		return err
+	}
+
+	delete(r.configs, id)
+	return nil
+}
+
+func (r *memoryLixiRepository) SetActive(ctx context.Context, id string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	target, err := r.checkVersion(id, version)
+	if err != nil {
+		return err
+	}
+
+	// Only one config may be active at a time
+	for _, config := range r.configs {
+		if config.IsActive && config != target {
+			config.IsActive = false
+			config.Version++
+		}
+	}
+	target.IsActive = true
+	target.Version++
+	return nil
+}
+
+func (r *memoryLixiRepository) Deactivate(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, exists := r.configs[id]
+	if !exists {
+		return domain.ErrLixiConfigNotFound
+	}
+
+	config.IsActive = false
+	config.Version++
+	return nil
+}
+
+func (r *memoryLixiRepository) RecordDraw(ctx context.Context, draw *domain.LixiDraw) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, exists := r.configs[draw.ConfigID]
+	if !exists {
+		return domain.ErrEnvelopeUnavailable
+	}
+
+	participant := draw.ConfigID + "/" + draw.ParticipantKey
+	if r.participants[participant] >= config.DrawsPerParticipant {
+		return domain.ErrDrawLimitReached
+	}
+
+	index := draw.EnvelopeID - 1
+	switch {
+	case !config.IsActive,
+		config.Budget.Currency != draw.Amount.Currency,
+		index < 0 || index >= len(config.Envelopes),
+		!config.Envelopes[index].InStock(),
+		!config.Budget.IsZero() && config.Spent.Amount+draw.Amount.Amount > config.Budget.Amount:
+		logging.FromContext(ctx).Debug("lixi envelope unavailable", "config_id", draw.ConfigID, "envelope_id", draw.EnvelopeID)
+		return domain.ErrEnvelopeUnavailable
+	}
+
+	r.participants[participant]++
+	config.Envelopes[index].Drawn++
+	config.Spent.Amount += draw.Amount.Amount
+
+	r.nextDrawID++
+	draw.ID = fmt.Sprintf("%d", r.nextDrawID)
+	draw.CreatedAt = time.Now()
+
+	stored := *draw
+	r.draws = append(r.draws, &stored)
+	return nil
+}
+
+func (r *memoryLixiRepository) GetParticipantDraws(ctx context.Context, configID, participantKey string) ([]*domain.LixiDraw, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var draws []*domain.LixiDraw
+	for _, draw := range r.draws {
+		if draw.ConfigID == configID && draw.ParticipantKey == participantKey {
+			d := *draw
+			draws = append(draws, &d)
+		}
+	}
+	return draws, nil
+}
+
+func (r *memoryLixiRepository) GetDrawStats(ctx context.Context, configID string) (*domain.DrawStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[int]*domain.EnvelopeDrawCount)
+	for _, draw := range r.draws {
+		if draw.ConfigID != configID {
+			continue
+		}
+		count, ok := counts[draw.EnvelopeID]
+		if !ok {
+			count = &domain.EnvelopeDrawCount{EnvelopeID: draw.EnvelopeID}
+			counts[draw.EnvelopeID] = count
+		}
+		count.Draws++
+		count.PaidOut += draw.Amount.Amount
+	}
+
+	stats := &domain.DrawStats{}
+	for _, count := range counts {
+		stats.Envelopes = append(stats.Envelopes, *count)
+	}
+	sort.Slice(stats.Envelopes, func(i, j int) bool {
+		return stats.Envelopes[i].EnvelopeID < stats.Envelopes[j].EnvelopeID
+	})
+
+	prefix := configID + "/"
+	for participant := range r.participants {
+		if strings.HasPrefix(participant, prefix) {
+			stats.Participants++
+		}
+	}
+	return stats, nil
+}
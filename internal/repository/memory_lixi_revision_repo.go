@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+type memoryLixiRevisionRepository struct {
+	mu        sync.RWMutex
+	revisions map[string][]domain.LixiConfigRevision // By config ID, oldest first
+}
+
+// NewMemoryLixiRevisionRepository creates a LixiRevisionRepository that keeps
+// revisions in process memory, for running without Postgres
+func NewMemoryLixiRevisionRepository() domain.LixiRevisionRepository {
+	return &memoryLixiRevisionRepository{revisions: make(map[string][]domain.LixiConfigRevision)}
+}
+
+// copyRevision returns a deep copy so callers never share state with the store
+func copyRevision(revision domain.LixiConfigRevision) *domain.LixiConfigRevision {
+	revision.Envelopes = slices.Clone(revision.Envelopes)
+	return &revision
+}
+
+func (r *memoryLixiRevisionRepository) Create(ctx context.Context, revision *domain.LixiConfigRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revision.Revision = len(r.revisions[revision.ConfigID]) + 1
+	revision.CreatedAt = time.Now()
+
+	r.revisions[revision.ConfigID] = append(r.revisions[revision.ConfigID], *copyRevision(*revision))
+	return nil
+}
+
+func (r *memoryLixiRevisionRepository) List(ctx context.Context, configID string) ([]*domain.LixiConfigRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored := r.revisions[configID]
+	revisions := make([]*domain.LixiConfigRevision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revisions = append(revisions, copyRevision(stored[i]))
+	}
+	return revisions, nil
+}
+
+func (r *memoryLixiRevisionRepository) Get(ctx context.Context, configID string, revision int) (*domain.LixiConfigRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored := r.revisions[configID]
+	if revision < 1 || revision > len(stored) {
+		return nil, domain.ErrRevisionNotFound
+	}
+	return copyRevision(stored[revision-1]), nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+// maxMemoryBuckets bounds the limiter's memory; full buckets are dropped
+// once it is exceeded
+const maxMemoryBuckets = 100_000
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+// NewMemoryRateLimiter creates a RateLimiter that keeps buckets in process
+// memory, so each API instance enforces its own limits
+func NewMemoryRateLimiter() domain.RateLimiter {
+	return &memoryRateLimiter{buckets: make(map[string]*memoryBucket)}
+}
+
+func (r *memoryRateLimiter) Allow(ctx context.Context, key string, limit domain.RateLimit) (bool, time.Duration, error) {
+	if limit.Disabled() {
+		return true, 0, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	bucket, exists := r.buckets[key]
+	if !exists {
+		if len(r.buckets) >= maxMemoryBuckets {
+			r.pruneFull(now)
+		}
+		bucket = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		r.buckets[key] = bucket
+	}
+
+	tokens, allowed, retryAfter := takeToken(bucket.tokens, bucket.updatedAt, now, limit)
+	bucket.tokens, bucket.updatedAt = tokens, now
+	bucket.fullAt = bucketFullAt(tokens, now, limit)
+
+	return allowed, retryAfter, nil
+}
+
+// pruneFull drops buckets that have refilled; the caller must hold the lock
+func (r *memoryRateLimiter) pruneFull(now time.Time) {
+	for key, bucket := range r.buckets {
+		if !bucket.fullAt.After(now) {
+			delete(r.buckets, key)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"my_backend/internal/domain"
 	"sync"
+	"time"
 )
 
 type memoryUserRepository struct {
@@ -23,21 +23,96 @@ func (r *memoryUserRepository) Create(ctx context.Context, user *domain.User) er
 	defer r.mu.Unlock()
 
 	if _, exists := r.users[user.Email]; exists {
-		return errors.New("user already exists")
+		return domain.ErrUserExists
 	}
 
 	r.users[user.Email] = user
 	return nil
 }
 
+func (r *memoryUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
 func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	user, exists := r.users[email]
 	if !exists {
-		return nil, errors.New("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 
 	return user, nil
 }
+
+func (r *memoryUserRepository) UpdateRole(ctx context.Context, email string, role domain.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[email]
+	if !exists {
+		return domain.ErrUserNotFound
+	}
+
+	user.Role = role
+	return nil
+}
+
+func (r *memoryUserRepository) RecordFailedLogin(ctx context.Context, id string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	user.FailedLogins++
+	return user.FailedLogins, nil
+}
+
+func (r *memoryUserRepository) LockUntil(ctx context.Context, id string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return err
+	}
+
+	user.LockedUntil = &until
+	return nil
+}
+
+func (r *memoryUserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return err
+	}
+
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	return nil
+}
+
+// byID finds a user by ID; the caller must hold the lock
+func (r *memoryUserRepository) byID(id string) (*domain.User, error) {
+	for _, user := range r.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
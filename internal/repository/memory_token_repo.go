@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+type memoryTokenRepository struct {
+	mu            sync.Mutex
+	refreshTokens map[string]*domain.RefreshToken // by token hash
+	revoked       map[string]time.Time            // jti -> expiry
+	nextID        int64
+}
+
+// NewMemoryTokenRepository creates a TokenRepository that keeps tokens in
+// process memory, for running without Postgres
+func NewMemoryTokenRepository() domain.TokenRepository {
+	return &memoryTokenRepository{
+		refreshTokens: make(map[string]*domain.RefreshToken),
+		revoked:       make(map[string]time.Time),
+	}
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	token.ID = fmt.Sprintf("%d", r.nextID)
+	token.CreatedAt = time.Now()
+
+	stored := *token
+	r.refreshTokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (r *memoryTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.refreshTokens[tokenHash]
+	if !exists {
+		return nil, domain.NotFound("refresh token not found")
+	}
+
+	t := *token
+	return &t, nil
+}
+
+func (r *memoryTokenRepository) UseRefreshToken(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.refreshTokens {
+		if token.ID != id {
+			continue
+		}
+		if token.UsedAt != nil || token.RevokedAt != nil {
+			return domain.ErrRefreshTokenReused
+		}
+		now := time.Now()
+		token.UsedAt = &now
+		return nil
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+func (r *memoryTokenRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	r.revokeWhere(func(token *domain.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeUserTokens(ctx context.Context, userID string) error {
+	r.revokeWhere(func(token *domain.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
+
+func (r *memoryTokenRepository) revokeWhere(match func(*domain.RefreshToken) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range r.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+		}
+	}
+}
+
+func (r *memoryTokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoked[jti] = expiresAt
+
+	now := time.Now()
+	for id, expiry := range r.revoked {
+		if expiry.Before(now) {
+			delete(r.revoked, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, revoked := r.revoked[jti]
+	return revoked, nil
+}
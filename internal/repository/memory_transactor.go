@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"my_backend/internal/domain"
+)
+
+type memoryTransactor struct{}
+
+// NewMemoryTransactor returns a Transactor for the memory repositories. Each
+// memory repository call is atomic on its own, but a failing fn does not roll
+// back the calls it already made.
+func NewMemoryTransactor() domain.Transactor {
+	return memoryTransactor{}
+}
+
+func (memoryTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
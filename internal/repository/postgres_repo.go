@@ -3,55 +3,168 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
 	"my_backend/internal/database"
 	"my_backend/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
-type postgresUserRepository struct{}
+type postgresUserRepository struct {
+	db database.DBTX
+}
 
 // NewPostgresUserRepository creates a new PostgreSQL user repository
-func NewPostgresUserRepository() domain.UserRepository {
-	return &postgresUserRepository{}
+func NewPostgresUserRepository(db database.DBTX) domain.UserRepository {
+	return &postgresUserRepository{db: db}
 }
 
 func (r *postgresUserRepository) Create(ctx context.Context, user *domain.User) error {
 	query := `
-		INSERT INTO users (email, password_hash)
-		VALUES ($1, $2)
+		INSERT INTO users (email, password_hash, role)
+		VALUES ($1, $2, $3)
 		RETURNING id
 	`
 
-	var id int
-	err := database.DB.QueryRow(ctx, query, user.Email, user.Password).Scan(&id)
+	var id int64
+	err := database.Conn(ctx, r.db).QueryRow(ctx, query, user.Email, user.Password, user.Role).Scan(&id)
 	if err != nil {
 		// Check for unique violation
-		if err.Error() == "ERROR: duplicate key value violates unique constraint \"users_email_key\" (SQLSTATE 23505)" {
-			return errors.New("user already exists")
+		if isUniqueViolation(err) {
+			return domain.ErrUserExists
 		}
 		return err
 	}
 
-	user.ID = string(rune(id))
+	user.ID = fmt.Sprintf("%d", id)
 	return nil
 }
 
+func (r *postgresUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	query := `
+		SELECT id, email, password_hash, role, failed_logins, locked_until
+		FROM users
+		WHERE id = $1
+	`
+
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	var user domain.User
+	err = database.Conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(&userID, &user.Email, &user.Password, &user.Role, &user.FailedLogins, &user.LockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	user.ID = fmt.Sprintf("%d", userID)
+	return &user, nil
+}
+
 func (r *postgresUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
-		SELECT id, email, password_hash
+		SELECT id, email, password_hash, role, failed_logins, locked_until
 		FROM users
 		WHERE email = $1
 	`
 
 	var user domain.User
-	var id int
-	err := database.DB.QueryRow(ctx, query, email).Scan(&id, &user.Email, &user.Password)
+	var id int64
+	err := database.Conn(ctx, r.db).QueryRow(ctx, query, email).Scan(&id, &user.Email, &user.Password, &user.Role, &user.FailedLogins, &user.LockedUntil)
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return nil, errors.New("user not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
 
-	user.ID = string(rune(id))
+	user.ID = fmt.Sprintf("%d", id)
 	return &user, nil
 }
+
+func (r *postgresUserRepository) UpdateRole(ctx context.Context, email string, role domain.Role) error {
+	query := `UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE email = $2`
+
+	result, err := database.Conn(ctx, r.db).Exec(ctx, query, role, email)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) RecordFailedLogin(ctx context.Context, id string) (int, error) {
+	query := `UPDATE users SET failed_logins = failed_logins + 1 WHERE id = $1 RETURNING failed_logins`
+
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, domain.ErrUserNotFound
+	}
+
+	var failures int
+	err = database.Conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(&failures)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, domain.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (r *postgresUserRepository) LockUntil(ctx context.Context, id string, until time.Time) error {
+	query := `UPDATE users SET locked_until = $1 WHERE id = $2`
+
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+
+	result, err := database.Conn(ctx, r.db).Exec(ctx, query, until, userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	query := `UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = $1`
+
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+
+	if _, err := database.Conn(ctx, r.db).Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+
+	return nil
+}
+
+// uniqueViolation is the Postgres SQLSTATE of a unique constraint failure
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a Postgres unique_violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
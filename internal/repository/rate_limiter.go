@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"my_backend/internal/database"
+	"my_backend/internal/domain"
+)
+
+type postgresRateLimiter struct {
+	db database.DBTX
+}
+
+// NewPostgresRateLimiter creates a RateLimiter whose buckets live in Postgres,
+// so that every API instance enforces the same limits
+func NewPostgresRateLimiter(db database.DBTX) domain.RateLimiter {
+	return &postgresRateLimiter{db: db}
+}
+
+func (r *postgresRateLimiter) Allow(ctx context.Context, key string, limit domain.RateLimit) (bool, time.Duration, error) {
+	if limit.Disabled() {
+		return true, 0, nil
+	}
+
+	tx, err := database.Conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Start from a full bucket on first use, then lock it so concurrent
+	// requests for the same key take tokens one after another
+	insertQuery := `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+		VALUES ($1, $2, clock_timestamp(), clock_timestamp())
+		ON CONFLICT (key) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, insertQuery, key, float64(limit.Burst)); err != nil {
+		return false, 0, fmt.Errorf("failed to create rate limit bucket: %w", err)
+	}
+
+	var tokens float64
+	var updatedAt, now time.Time
+	selectQuery := `SELECT tokens, updated_at, clock_timestamp() FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`
+	if err := tx.QueryRow(ctx, selectQuery, key).Scan(&tokens, &updatedAt, &now); err != nil {
+		return false, 0, fmt.Errorf("failed to read rate limit bucket: %w", err)
+	}
+
+	tokens, allowed, retryAfter := takeToken(tokens, updatedAt, now, limit)
+
+	updateQuery := `UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1`
+	if _, err := tx.Exec(ctx, updateQuery, key, tokens, now, bucketFullAt(tokens, now, limit)); err != nil {
+		return false, 0, fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Full buckets behave like missing ones, so they can be dropped at any time
+	if rand.IntN(100) == 0 {
+		if _, err := database.Conn(ctx, r.db).Exec(ctx, `DELETE FROM rate_limit_buckets WHERE full_at < clock_timestamp()`); err != nil {
+			return allowed, retryAfter, fmt.Errorf("failed to prune rate limit buckets: %w", err)
+		}
+	}
+
+	return allowed, retryAfter, nil
+}
+
+// takeToken refills a bucket that held tokens at updatedAt up to now, then
+// takes one token if available. It returns the tokens left and, when none was
+// available, how long until one is.
+func takeToken(tokens float64, updatedAt, now time.Time, limit domain.RateLimit) (float64, bool, time.Duration) {
+	rate := float64(limit.Burst) / limit.Per.Seconds() // Tokens per second
+
+	elapsed := math.Max(0, now.Sub(updatedAt).Seconds())
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed*rate)
+
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	return tokens, false, time.Duration((1 - tokens) / rate * float64(time.Second))
+}
+
+// bucketFullAt returns when a bucket holding tokens at now is full again
+func bucketFullAt(tokens float64, now time.Time, limit domain.RateLimit) time.Time {
+	rate := float64(limit.Burst) / limit.Per.Seconds()
+	return now.Add(time.Duration((float64(limit.Burst) - tokens) / rate * float64(time.Second)))
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+func TestTakeToken(t *testing.T) {
+	limit := domain.RateLimit{Burst: 10, Per: time.Minute} // One token every 6s
+	start := time.Date(2025, 1, 29, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		wantTokens float64
+		wantOK     bool
+		wantRetry  time.Duration
+	}{
+		{"full bucket", 10, 0, 9, true, 0},
+		{"last token", 1, 0, 0, true, 0},
+		{"empty bucket", 0, 0, 0, false, 6 * time.Second},
+		{"partly refilled", 0, 3 * time.Second, 0.5, false, 3 * time.Second},
+		{"refilled one token", 0, 6 * time.Second, 0, true, 0},
+		{"refill is capped at burst", 5, time.Hour, 9, true, 0},
+		{"clock going backwards refills nothing", 0.5, -time.Minute, 0.5, false, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, ok, retry := takeToken(tt.tokens, start, start.Add(tt.elapsed), limit)
+			if math.Abs(tokens-tt.wantTokens) > 1e-9 || ok != tt.wantOK || (retry-tt.wantRetry).Abs() > time.Millisecond {
+				t.Errorf("takeToken = %v, %t, %s, want %v, %t, %s", tokens, ok, retry, tt.wantTokens, tt.wantOK, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestBucketFullAt(t *testing.T) {
+	limit := domain.RateLimit{Burst: 5, Per: time.Hour} // One token every 12m
+	now := time.Date(2025, 1, 29, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		tokens float64
+		want   time.Duration
+	}{
+		{5, 0},
+		{4, 12 * time.Minute},
+		{0, time.Hour},
+		{2.5, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := bucketFullAt(tt.tokens, now, limit).Sub(now); (got - tt.want).Abs() > time.Millisecond {
+			t.Errorf("bucketFullAt(%v tokens) = now + %s, want now + %s", tt.tokens, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryRateLimiter(t *testing.T) {
+	limiter := NewMemoryRateLimiter()
+	limit := domain.RateLimit{Burst: 3, Per: time.Hour}
+
+	for i := range 3 {
+		if ok, _, err := limiter.Allow(t.Context(), "login:1.2.3.4", limit); !ok || err != nil {
+			t.Fatalf("request %d: allowed = %t, %v, want allowed", i+1, ok, err)
+		}
+	}
+
+	ok, retry, err := limiter.Allow(t.Context(), "login:1.2.3.4", limit)
+	if ok || err != nil {
+		t.Fatalf("request over the burst: allowed = %t, %v", ok, err)
+	}
+	if retry <= 19*time.Minute || retry > 20*time.Minute {
+		t.Errorf("Retry-After = %s, want just under 20m", retry)
+	}
+
+	// Keys are independent
+	if ok, _, _ := limiter.Allow(t.Context(), "login:5.6.7.8", limit); !ok {
+		t.Error("another key was limited")
+	}
+
+	// A zero burst disables the limit
+	for range 10 {
+		if ok, _, _ := limiter.Allow(t.Context(), "login:1.2.3.4", domain.RateLimit{}); !ok {
+			t.Fatal("disabled limit refused a request")
+		}
+	}
+}
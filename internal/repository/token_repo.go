@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"my_backend/internal/database"
+	"my_backend/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type postgresTokenRepository struct {
+	db database.DBTX
+}
+
+func NewPostgresTokenRepository(db database.DBTX) domain.TokenRepository {
+	return &postgresTokenRepository{db: db}
+}
+
+func (r *postgresTokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	userID, err := strconv.ParseInt(token.UserID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user id %q", token.UserID)
+	}
+
+	var id int64
+	err = database.Conn(ctx, r.db).QueryRow(ctx, query, userID, token.FamilyID, token.TokenHash, token.ExpiresAt).Scan(&id, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	token.ID = fmt.Sprintf("%d", id)
+	return nil
+}
+
+func (r *postgresTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var token domain.RefreshToken
+	var id, userID int64
+	err := database.Conn(ctx, r.db).QueryRow(ctx, query, tokenHash).Scan(&id, &userID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.NotFound("refresh token not found")
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	token.ID = fmt.Sprintf("%d", id)
+	token.UserID = fmt.Sprintf("%d", userID)
+	return &token, nil
+}
+
+func (r *postgresTokenRepository) UseRefreshToken(ctx context.Context, id string) error {
+	// Conditional update so that two concurrent refreshes cannot both win
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+	`
+
+	result, err := database.Conn(ctx, r.db).Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+func (r *postgresTokenRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL`
+
+	if _, err := database.Conn(ctx, r.db).Exec(ctx, query, familyID); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresTokenRepository) RevokeUserTokens(ctx context.Context, userID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`
+
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user id %q", userID)
+	}
+
+	if _, err := database.Conn(ctx, r.db).Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresTokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := database.Conn(ctx, r.db).Exec(ctx, query, jti, expiresAt); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	// Entries are only needed until the token would have expired anyway
+	if _, err := database.Conn(ctx, r.db).Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("failed to purge revoked tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	var revoked bool
+	if err := database.Conn(ctx, r.db).QueryRow(ctx, query, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+
+	return revoked, nil
+}
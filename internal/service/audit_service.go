@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"my_backend/internal/domain"
+)
+
+type auditService struct {
+	auditRepo domain.AuditRepository
+}
+
+func NewAuditService(auditRepo domain.AuditRepository) domain.AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+func (s *auditService) List(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = domain.DefaultAuditPageSize
+	case filter.Limit < 1 || filter.Limit > domain.MaxAuditPageSize:
+		return nil, domain.Invalid("limit", fmt.Sprintf("limit must be between 1 and %d", domain.MaxAuditPageSize))
+	}
+	if filter.TargetID != "" && filter.TargetType == "" {
+		return nil, domain.Invalid("target_type", "target_type is required with target_id")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, domain.Invalid("to", "to must be after from")
+	}
+
+	page, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return empty array instead of null
+	if page.Entries == nil {
+		page.Entries = []*domain.AuditEntry{}
+	}
+	return page, nil
+}
+
+// recordAudit appends an entry for a change made by the user and request in
+// ctx. before and after are snapshots of the target (nil when it did not or
+// no longer exists); only the fields that differ are stored. Call it with the
+// ctx of the transaction making the change, so neither is kept without the
+// other.
+func recordAudit(ctx context.Context, auditRepo domain.AuditRepository, action, targetType, targetID string, before, after any) error {
+	changes, err := auditChanges(before, after)
+	if err != nil {
+		return err
+	}
+
+	request := domain.RequestInfoFromContext(ctx)
+	entry := &domain.AuditEntry{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    changes,
+		RequestID:  request.ID,
+		IP:         request.IP,
+	}
+	if user, ok := domain.UserFromContext(ctx); ok {
+		entry.ActorID, entry.Actor = user.ID, user.Email
+	}
+
+	return auditRepo.Create(ctx, entry)
+}
+
+// auditChanges diffs the JSON encodings of before and after
+func auditChanges(before, after any) (map[string]domain.AuditChange, error) {
+	decode := func(v any) (any, error) {
+		if v == nil {
+			return nil, nil
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal audit snapshot: %w", err)
+		}
+		var decoded any
+		return decoded, json.Unmarshal(data, &decoded)
+	}
+
+	b, err := decode(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := decode(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]domain.AuditChange)
+	diffJSON("", b, a, changes)
+	return changes, nil
+}
+
+// diffJSON records in changes every path where before and after differ.
+// Objects are compared field by field and arrays of the same length element
+// by element; anything else is recorded whole. A nil side stands for an
+// absent value.
+func diffJSON(path string, before, after any, changes map[string]domain.AuditChange) {
+	beforeObj, beforeIsObj := before.(map[string]any)
+	afterObj, afterIsObj := after.(map[string]any)
+	if (beforeIsObj || before == nil) && (afterIsObj || after == nil) && (beforeIsObj || afterIsObj) {
+		var keys []string
+		for key := range beforeObj {
+			keys = append(keys, key)
+		}
+		for key := range afterObj {
+			if _, ok := beforeObj[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			diffJSON(field, beforeObj[key], afterObj[key], changes)
+		}
+		return
+	}
+
+	beforeArr, beforeIsArr := before.([]any)
+	afterArr, afterIsArr := after.([]any)
+	if beforeIsArr && afterIsArr && len(beforeArr) == len(afterArr) {
+		for i := range beforeArr {
+			diffJSON(fmt.Sprintf("%s[%d]", path, i), beforeArr[i], afterArr[i], changes)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+
+	encode := func(v any) json.RawMessage {
+		if v == nil {
+			return nil
+		}
+		data, _ := json.Marshal(v)
+		return data
+	}
+	changes[path] = domain.AuditChange{Before: encode(before), After: encode(after)}
+}
@@ -2,25 +2,42 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"my_backend/internal/domain"
+	"my_backend/internal/logging"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type authService struct {
-	userRepo  domain.UserRepository
-	jwtSecret []byte
+	userRepo     domain.UserRepository
+	tokenRepo    domain.TokenRepository
+	auditRepo    domain.AuditRepository
+	transactor   domain.Transactor
+	limiter      domain.RateLimiter
+	accountLimit domain.RateLimit // Login attempts per email address
+	jwtSecret    []byte
 }
 
-func NewAuthService(userRepo domain.UserRepository, jwtSecret string) domain.AuthService {
+// NewAuthService creates the auth service; role changes and session
+// revocations are recorded in auditRepo
+func NewAuthService(userRepo domain.UserRepository, tokenRepo domain.TokenRepository, auditRepo domain.AuditRepository, transactor domain.Transactor, limiter domain.RateLimiter, accountLimit domain.RateLimit, jwtSecret string) domain.AuthService {
 	return &authService{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		auditRepo:    auditRepo,
+		transactor:   transactor,
+		limiter:      limiter,
+		accountLimit: accountLimit,
+		jwtSecret:    []byte(jwtSecret),
 	}
 }
 
@@ -34,6 +51,7 @@ func (s *authService) Register(ctx context.Context, email, password string) (*do
 		ID:       time.Now().String(), // Simple ID generation
 		Email:    email,
 		Password: string(hashedPassword),
+		Role:     domain.RoleUser, // No admin access until an admin grants a role
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
@@ -43,26 +61,331 @@ func (s *authService) Register(ctx context.Context, email, password string) (*do
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (*domain.User, string, error) {
+// Access tokens are short-lived; sessions are kept alive by rotating refresh tokens
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// After maxFailedLogins consecutive failures an account is locked for
+// baseLockout, doubling with every further failure up to maxLockout
+const (
+	maxFailedLogins = 5
+	baseLockout     = time.Minute
+	maxLockout      = time.Hour
+)
+
+func (s *authService) Login(ctx context.Context, email, password string) (*domain.User, *domain.TokenPair, error) {
+	// Throttle per account as well as per IP, so a distributed attack on one
+	// account (or on unknown emails) is slowed down too. Like the per-IP
+	// limit this fails open; the account lockout below still applies
+	allowed, retryAfter, err := s.limiter.Allow(ctx, "login-account:"+email, s.accountLimit)
+	if err != nil {
+		logging.FromContext(ctx).Error("rate limiter failed, allowing login", "error", err)
+	} else if !allowed {
+		return nil, nil, &domain.RateLimitError{Message: "too many login attempts, try again later", RetryAfter: retryAfter}
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return nil, "", errors.New("invalid credentials")
+		return nil, nil, domain.Unauthorized("invalid credentials")
+	}
+
+	if user.LockedUntil != nil {
+		if wait := time.Until(*user.LockedUntil); wait > 0 {
+			return nil, nil, &domain.RateLimitError{Message: "account temporarily locked after too many failed logins", RetryAfter: wait}
+		}
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, "", errors.New("invalid credentials")
+		if err := s.recordFailedLogin(ctx, user); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, domain.Unauthorized("invalid credentials")
+	}
+
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueTokens(ctx, user, familyID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Create JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+	return user, tokens, nil
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*domain.User, *domain.TokenPair, error) {
+	var (
+		user   *domain.User
+		tokens *domain.TokenPair
+		reused *domain.RefreshToken // Set when the token was already spent
+	)
+
+	// Spending the token and issuing its successor commit together, so a
+	// failure in between can't leave the session without a valid token
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		stored, err := s.tokenRepo.GetRefreshToken(ctx, hashToken(refreshToken))
+		if err != nil {
+			return domain.Unauthorized("invalid refresh token")
+		}
+
+		if stored.UsedAt != nil || stored.RevokedAt != nil {
+			reused = stored
+			return domain.ErrRefreshTokenReused
+		}
+		if !time.Now().Before(stored.ExpiresAt) {
+			return domain.Unauthorized("refresh token has expired")
+		}
+
+		if err := s.tokenRepo.UseRefreshToken(ctx, stored.ID); err != nil {
+			if errors.Is(err, domain.ErrRefreshTokenReused) {
+				// Lost the race against another refresh with the same token
+				reused = stored
+			}
+			return err
+		}
+
+		// Reload the user so role changes take effect on the next access token
+		user, err = s.userRepo.GetByID(ctx, stored.UserID)
+		if err != nil {
+			return domain.Unauthorized("invalid refresh token")
+		}
+
+		tokens, err = s.issueTokens(ctx, user, stored.FamilyID)
+		return err
 	})
+	if reused != nil {
+		// Outside the transaction, which rolled back, so the revocation sticks
+		return nil, nil, s.revokeReusedFamily(ctx, reused)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// recordFailedLogin counts a wrong password and locks the account with
+// exponential backoff once there were too many in a row
+func (s *authService) recordFailedLogin(ctx context.Context, user *domain.User) error {
+	failures, err := s.userRepo.RecordFailedLogin(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	lockout := lockoutAfter(failures)
+	if lockout == 0 {
+		return nil
+	}
+
+	logging.FromContext(ctx).Warn("locking account after failed logins", "user_id", user.ID, "failures", failures, "lockout", lockout.String())
+	return s.userRepo.LockUntil(ctx, user.ID, time.Now().Add(lockout))
+}
+
+// lockoutAfter returns how long an account is locked after failures
+// consecutive failed logins, 0 while below maxFailedLogins
+func lockoutAfter(failures int) time.Duration {
+	if failures < maxFailedLogins {
+		return 0
+	}
+	if shift := failures - maxFailedLogins; shift < 6 { // 2^6 minutes already exceeds maxLockout
+		return min(baseLockout<<shift, maxLockout)
+	}
+	return maxLockout
+}
 
-	tokenString, err := token.SignedString(s.jwtSecret)
+// revokeReusedFamily ends every session descending from a refresh token that
+// was presented twice, since one of the holders must have stolen it
+func (s *authService) revokeReusedFamily(ctx context.Context, stored *domain.RefreshToken) error {
+	logging.FromContext(ctx).Warn("refresh token reused, revoking session", "user_id", stored.UserID, "family_id", stored.FamilyID)
+	if err := s.tokenRepo.RevokeTokenFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+func (s *authService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	claims, err := s.parseToken(accessToken)
 	if err != nil {
-		return nil, "", err
+		return err
 	}
 
-	return user, tokenString, nil
+	if refreshToken != "" {
+		stored, err := s.tokenRepo.GetRefreshToken(ctx, hashToken(refreshToken))
+		if err != nil || stored.UserID != claims.UserID {
+			return domain.Unauthorized("invalid refresh token")
+		}
+		if err := s.tokenRepo.RevokeTokenFamily(ctx, stored.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	return s.tokenRepo.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// accessClaims are the JWT claims of an access token
+type accessClaims struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// issueTokens signs a new access token and stores a new refresh token in familyID
+func (s *authService) issueTokens(ctx context.Context, user *domain.User, familyID string) (*domain.TokenPair, error) {
+	now := time.Now()
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	accessExpiresAt := now.Add(accessTokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Role:   string(user.Role),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(accessExpiresAt),
+		},
+	})
+
+	accessToken, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(refreshTokenTTL),
+	}
+	if err := s.tokenRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// parseToken verifies the signature and expiry of an access token
+func (s *authService) parseToken(tokenString string) (*accessClaims, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (interface{}, error) {
+		return s.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, domain.Unauthorized("token has expired")
+		}
+		return nil, domain.Unauthorized("invalid token")
+	}
+
+	// Tokens issued before revocation support carry no jti and can't be revoked
+	if claims.UserID == "" || claims.ID == "" || !domain.Role(claims.Role).Valid() {
+		return nil, domain.Unauthorized("invalid token")
+	}
+
+	return &claims, nil
+}
+
+func (s *authService) ValidateToken(ctx context.Context, tokenString string) (*domain.User, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.tokenRepo.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, domain.Unauthorized("token has been revoked")
+	}
+
+	return &domain.User{
+		ID:    claims.UserID,
+		Email: claims.Email,
+		Role:  domain.Role(claims.Role),
+	}, nil
+}
+
+// randomToken returns n random bytes, base64url-encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is how refresh tokens are looked up without storing them
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *authService) SetRole(ctx context.Context, email string, role domain.Role) error {
+	if email == "" {
+		return domain.Invalid("email", "email is required")
+	}
+	if !role.Valid() {
+		return domain.Invalid("role", "invalid role")
+	}
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		previous := user.Role
+		if previous == role {
+			return nil // Nothing to change or audit, e.g. the admin seeded at startup
+		}
+
+		if err := s.userRepo.UpdateRole(ctx, email, role); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo, domain.AuditUserSetRole, domain.AuditTargetUser, user.ID,
+			userRole{previous}, userRole{role})
+	})
+}
+
+// userRole is the audit snapshot of a role change
+type userRole struct {
+	Role domain.Role `json:"role"`
+}
+
+func (s *authService) RevokeSessions(ctx context.Context, email string) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+
+		if err := s.tokenRepo.RevokeUserTokens(ctx, user.ID); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo, domain.AuditUserRevoke, domain.AuditTargetUser, user.ID, nil, nil)
+	})
 }
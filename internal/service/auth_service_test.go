@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/repository"
+)
+
+func newTestAuthService(limiter domain.RateLimiter) (domain.AuthService, domain.UserRepository) {
+	userRepo := repository.NewMemoryUserRepository()
+	return NewAuthService(
+		userRepo,
+		repository.NewMemoryTokenRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemoryTransactor(),
+		limiter,
+		domain.RateLimit{Burst: 100, Per: time.Minute},
+		"0123456789abcdef0123456789abcdef",
+	), userRepo
+}
+
+func TestLockoutAfter(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{10, 32 * time.Minute},
+		{11, time.Hour}, // 64m capped
+		{12, time.Hour},
+		{1000, time.Hour}, // No overflow of the shift
+	}
+	for _, tt := range tests {
+		if got := lockoutAfter(tt.failures); got != tt.want {
+			t.Errorf("lockoutAfter(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginLocksAccount(t *testing.T) {
+	ctx := context.Background()
+	auth, userRepo := newTestAuthService(repository.NewMemoryRateLimiter())
+	if _, err := auth.Register(ctx, "a@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range maxFailedLogins {
+		if _, _, err := auth.Login(ctx, "a@example.com", "wrong"); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Fatalf("failed login %d: error = %v, want unauthorized", i+1, err)
+		}
+	}
+
+	user, err := userRepo.GetByEmail(ctx, "a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.LockedUntil == nil || time.Until(*user.LockedUntil) <= 50*time.Second || time.Until(*user.LockedUntil) > time.Minute {
+		t.Fatalf("locked until %v, want about a minute from now", user.LockedUntil)
+	}
+
+	// Even the right password is refused while locked
+	var limited *domain.RateLimitError
+	if _, _, err := auth.Login(ctx, "a@example.com", "password123"); !errors.As(err, &limited) || limited.RetryAfter <= 0 {
+		t.Errorf("login while locked: error = %v, want a rate limit error with Retry-After", err)
+	}
+}
+
+// failingLimiter is a rate limiter whose store is down
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit domain.RateLimit) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestLoginWhenLimiterFails(t *testing.T) {
+	ctx := context.Background()
+	auth, _ := newTestAuthService(failingLimiter{})
+	if _, err := auth.Register(ctx, "a@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := auth.Login(ctx, "a@example.com", "password123"); err != nil {
+		t.Errorf("login with a failing limiter: %v, want it to fail open", err)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"my_backend/internal/domain"
+)
+
+// recordRevision saves the admin-editable fields of config as its next
+// revision; run it in the transaction saving config
+func (s *lixiService) recordRevision(ctx context.Context, config *domain.LixiConfig, restoredFrom *int) error {
+	envelopes := slices.Clone(config.Envelopes)
+	for i := range envelopes {
+		envelopes[i].Drawn = 0 // Server-managed, not part of a revision
+	}
+
+	revision := &domain.LixiConfigRevision{
+		ConfigID:            config.ID,
+		Name:                config.Name,
+		Envelopes:           envelopes,
+		Budget:              config.Budget,
+		DrawsPerParticipant: config.DrawsPerParticipant,
+		StartsAt:            config.StartsAt,
+		EndsAt:              config.EndsAt,
+		RestoredFrom:        restoredFrom,
+	}
+	if user, ok := domain.UserFromContext(ctx); ok {
+		revision.CreatedBy = user.Email
+	}
+
+	return s.revisionRepo.Create(ctx, revision)
+}
+
+func (s *lixiService) GetConfigRevisions(ctx context.Context, id string) ([]*domain.LixiConfigRevision, error) {
+	if id == "" {
+		return nil, domain.Invalid("id", "id is required")
+	}
+
+	// Tell a missing config apart from one without history
+	if _, err := s.lixiRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionRepo.List(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return empty array instead of null
+	if revisions == nil {
+		revisions = []*domain.LixiConfigRevision{}
+	}
+	return revisions, nil
+}
+
+func (s *lixiService) DiffConfigRevisions(ctx context.Context, id string, from, to int) (*domain.RevisionDiff, error) {
+	if id == "" {
+		return nil, domain.Invalid("id", "id is required")
+	}
+	if from < 0 || to < 0 {
+		return nil, domain.Invalid("from", "revisions must be positive")
+	}
+
+	if to == 0 {
+		revisions, err := s.GetConfigRevisions(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(revisions) == 0 {
+			return nil, domain.ErrRevisionNotFound
+		}
+		to = revisions[0].Revision
+	}
+	if from == 0 {
+		from = max(to-1, 1)
+	}
+
+	before, err := s.revisionRepo.Get(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.revisionRepo.Get(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffRevisions(before, after), nil
+}
+
+// diffRevisions compares the config-level fields of two revisions, then
+// their envelopes by ID
+func diffRevisions(before, after *domain.LixiConfigRevision) *domain.RevisionDiff {
+	diff := &domain.RevisionDiff{
+		ConfigID:  after.ConfigID,
+		From:      before.Revision,
+		To:        after.Revision,
+		Changes:   []domain.FieldChange{},
+		Envelopes: []domain.EnvelopeChange{},
+	}
+
+	diff.Changes = appendChange(diff.Changes, "name", before.Name, after.Name)
+	diff.Changes = appendChange(diff.Changes, "budget", before.Budget, after.Budget)
+	diff.Changes = appendChange(diff.Changes, "draws_per_participant", before.DrawsPerParticipant, after.DrawsPerParticipant)
+	diff.Changes = appendChange(diff.Changes, "starts_at", before.StartsAt, after.StartsAt)
+	diff.Changes = appendChange(diff.Changes, "ends_at", before.EndsAt, after.EndsAt)
+
+	envelopes := func(revision *domain.LixiConfigRevision) map[int]domain.LixiEnvelope {
+		byID := make(map[int]domain.LixiEnvelope, len(revision.Envelopes))
+		for _, env := range revision.Envelopes {
+			byID[env.ID] = env
+		}
+		return byID
+	}
+	beforeEnvelopes, afterEnvelopes := envelopes(before), envelopes(after)
+
+	var ids []int
+	for id := range beforeEnvelopes {
+		ids = append(ids, id)
+	}
+	for id := range afterEnvelopes {
+		if _, ok := beforeEnvelopes[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		b, inBefore := beforeEnvelopes[id]
+		a, inAfter := afterEnvelopes[id]
+
+		// Fields of a missing envelope compare as null
+		field := func(env domain.LixiEnvelope, present bool, value func(domain.LixiEnvelope) any) any {
+			if !present {
+				return nil
+			}
+			return value(env)
+		}
+
+		var changes []domain.FieldChange
+		for _, f := range []struct {
+			name  string
+			value func(domain.LixiEnvelope) any
+		}{
+			{"amount", func(e domain.LixiEnvelope) any { return e.Amount }},
+			{"message", func(e domain.LixiEnvelope) any { return e.Message }},
+			{"rate", func(e domain.LixiEnvelope) any { return e.Rate }},
+			{"quantity", func(e domain.LixiEnvelope) any { return e.Quantity }},
+		} {
+			changes = appendChange(changes, f.name, field(b, inBefore, f.value), field(a, inAfter, f.value))
+		}
+
+		if len(changes) > 0 {
+			diff.Envelopes = append(diff.Envelopes, domain.EnvelopeChange{EnvelopeID: id, Changes: changes})
+		}
+	}
+
+	return diff
+}
+
+// appendChange appends a FieldChange when before and after differ
+func appendChange(changes []domain.FieldChange, field string, before, after any) []domain.FieldChange {
+	if sameValue(before, after) {
+		return changes
+	}
+	return append(changes, domain.FieldChange{Field: field, Before: before, After: after})
+}
+
+// sameValue compares field values, treating nil times as equal to each other
+// and comparing non-nil times as instants
+func sameValue(before, after any) bool {
+	switch b := before.(type) {
+	case *time.Time:
+		a, ok := after.(*time.Time)
+		if !ok || b == nil || a == nil {
+			return ok && b == nil && a == nil
+		}
+		return b.Equal(*a)
+	}
+	return before == after
+}
+
+func (s *lixiService) RestoreConfigRevision(ctx context.Context, id string, version, revision int) (*domain.LixiConfig, error) {
+	if id == "" {
+		return nil, domain.Invalid("id", "id is required")
+	}
+
+	var config *domain.LixiConfig
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		rev, err := s.revisionRepo.Get(ctx, id, revision)
+		if err != nil {
+			return err
+		}
+
+		budget, drawsPerParticipant := rev.Budget, rev.DrawsPerParticipant
+		input := domain.LixiConfigInput{
+			Name:                rev.Name,
+			Budget:              &budget,
+			DrawsPerParticipant: &drawsPerParticipant,
+			Schedule:            &domain.LixiSchedule{StartsAt: rev.StartsAt, EndsAt: rev.EndsAt},
+			Envelopes:           rev.Envelopes,
+		}
+
+		config, err = s.updateConfig(ctx, id, version, input, domain.AuditConfigRestore, &rev.Revision)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/repository"
+)
+
+func newTestLixiService() domain.LixiService {
+	return NewLixiService(
+		repository.NewMemoryLixiRepository(),
+		repository.NewMemoryLixiGreetingRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemoryLixiRevisionRepository(),
+		repository.NewMemoryTransactor(),
+		nil, // Configs never touch images
+		nil,
+		"0123456789abcdef0123456789abcdef",
+		nil,
+	)
+}
+
+// validEnvelopes returns the 12 envelopes a config requires
+func validEnvelopes() []domain.LixiEnvelope {
+	envs := envelopes(1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	for i := range envs {
+		envs[i].Message = "Phát Tài Phát Lộc!"
+	}
+	return envs
+}
+
+func TestSameValue(t *testing.T) {
+	noon := time.Date(2025, 1, 29, 12, 0, 0, 0, time.UTC)
+	noonInHanoi := noon.In(time.FixedZone("ICT", 7*60*60))
+	later := noon.Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		before, after any
+		want          bool
+	}{
+		{"both times nil", (*time.Time)(nil), (*time.Time)(nil), true},
+		{"time set", (*time.Time)(nil), &noon, false},
+		{"time cleared", &noon, (*time.Time)(nil), false},
+		{"same instant in another zone", &noon, &noonInHanoi, true},
+		{"different instants", &noon, &later, false},
+		{"equal strings", "Tết", "Tết", true},
+		{"different strings", "Tết", "Tet", false},
+		{"equal money", domain.Money{Amount: 100, Currency: "VND"}, domain.Money{Amount: 100, Currency: "VND"}, true},
+		{"different currency", domain.Money{Amount: 100, Currency: "VND"}, domain.Money{Amount: 100, Currency: "USD"}, false},
+		{"missing envelope field", nil, 0.5, false},
+		{"both missing", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameValue(tt.before, tt.after); got != tt.want {
+				t.Errorf("sameValue(%v, %v) = %t, want %t", tt.before, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	startsAt := time.Date(2025, 1, 29, 0, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(72 * time.Hour)
+
+	before := &domain.LixiConfigRevision{
+		ConfigID:            "c1",
+		Revision:            1,
+		Name:                "Tết 2025",
+		Envelopes:           envelopes(1, 2),
+		Budget:              domain.Money{Amount: 1000000, Currency: "VND"},
+		DrawsPerParticipant: 1,
+		EndsAt:              &endsAt,
+	}
+	after := &domain.LixiConfigRevision{
+		ConfigID:            "c1",
+		Revision:            3,
+		Name:                "Tết 2025",
+		Envelopes:           envelopes(1, 5, 3)[1:], // Envelope 1 removed, 2 changed, 3 added
+		Budget:              domain.Money{Amount: 1000000, Currency: "VND"},
+		DrawsPerParticipant: 2,
+		StartsAt:            &startsAt,
+	}
+
+	diff := diffRevisions(before, after)
+	if diff.ConfigID != "c1" || diff.From != 1 || diff.To != 3 {
+		t.Errorf("diff of %s from %d to %d, want c1 from 1 to 3", diff.ConfigID, diff.From, diff.To)
+	}
+
+	wantChanges := map[string][2]any{
+		"draws_per_participant": {1, 2},
+		"starts_at":             {nil, startsAt},
+		"ends_at":               {endsAt, nil},
+	}
+	if len(diff.Changes) != len(wantChanges) {
+		t.Errorf("changes = %+v, want %d", diff.Changes, len(wantChanges))
+	}
+	for _, change := range diff.Changes {
+		want, ok := wantChanges[change.Field]
+		if !ok {
+			t.Errorf("unexpected change of %s: %v -> %v", change.Field, change.Before, change.After)
+			continue
+		}
+		if !sameValue(deref(change.Before), want[0]) || !sameValue(deref(change.After), want[1]) {
+			t.Errorf("%s changed %v -> %v, want %v -> %v", change.Field, deref(change.Before), deref(change.After), want[0], want[1])
+		}
+	}
+
+	if len(diff.Envelopes) != 3 {
+		t.Fatalf("envelope changes = %+v, want envelopes 1, 2 and 3", diff.Envelopes)
+	}
+
+	// A removed envelope has every field after as null
+	removed := diff.Envelopes[0]
+	if removed.EnvelopeID != 1 || len(removed.Changes) != 4 {
+		t.Errorf("removed envelope = %+v, want envelope 1 with 4 changes", removed)
+	}
+	for _, change := range removed.Changes {
+		if change.Before == nil || change.After != nil {
+			t.Errorf("removed envelope %s: %v -> %v, want a value -> null", change.Field, change.Before, change.After)
+		}
+	}
+
+	// Only the rate of envelope 2 changed
+	changed := diff.Envelopes[1]
+	if changed.EnvelopeID != 2 || len(changed.Changes) != 1 || changed.Changes[0].Field != "rate" ||
+		changed.Changes[0].Before != 2.0 || changed.Changes[0].After != 5.0 {
+		t.Errorf("changed envelope = %+v, want envelope 2 with rate 2 -> 5", changed)
+	}
+
+	// An added envelope has every field before as null
+	added := diff.Envelopes[2]
+	if added.EnvelopeID != 3 || len(added.Changes) != 4 {
+		t.Errorf("added envelope = %+v, want envelope 3 with 4 changes", added)
+	}
+	for _, change := range added.Changes {
+		if change.Before != nil || change.After == nil {
+			t.Errorf("added envelope %s: %v -> %v, want null -> a value", change.Field, change.Before, change.After)
+		}
+	}
+}
+
+func TestDiffRevisionsUnchanged(t *testing.T) {
+	at := time.Date(2025, 1, 29, 0, 0, 0, 0, time.UTC)
+	atInHanoi := at.In(time.FixedZone("ICT", 7*60*60))
+	before := &domain.LixiConfigRevision{Revision: 1, Name: "Tết", Envelopes: envelopes(1, 2), StartsAt: &at}
+	after := &domain.LixiConfigRevision{Revision: 2, Name: "Tết", Envelopes: envelopes(1, 2), StartsAt: &atInHanoi}
+
+	diff := diffRevisions(before, after)
+	if len(diff.Changes) != 0 || len(diff.Envelopes) != 0 {
+		t.Errorf("diff of identical revisions = %+v", diff)
+	}
+	// Empty arrays rather than null in JSON
+	if diff.Changes == nil || diff.Envelopes == nil {
+		t.Error("diff of identical revisions has nil slices")
+	}
+}
+
+// deref turns the *time.Time of a schedule change into a time.Time, and nil
+// pointers into nil, for comparing against expected values
+func deref(v any) any {
+	if t, ok := v.(*time.Time); ok {
+		if t == nil {
+			return nil
+		}
+		return *t
+	}
+	return v
+}
+
+func TestRestoreConfigRevision(t *testing.T) {
+	ctx := t.Context()
+	lixi := newTestLixiService()
+
+	created, err := lixi.CreateConfig(ctx, domain.LixiConfigInput{Name: "Tết 2025", Envelopes: validEnvelopes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endsAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	draws := 3
+	updated, err := lixi.UpdateConfig(ctx, created.ID, created.Version, domain.LixiConfigInput{
+		Name:                "Tết 2026",
+		DrawsPerParticipant: &draws,
+		Schedule:            &domain.LixiSchedule{EndsAt: &endsAt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring needs the current version
+	if _, err := lixi.RestoreConfigRevision(ctx, created.ID, updated.Version-1, 1); !errors.Is(err, domain.ErrLixiConfigModified) {
+		t.Errorf("restore at a stale version: error = %v, want ErrLixiConfigModified", err)
+	}
+	if _, err := lixi.RestoreConfigRevision(ctx, created.ID, updated.Version, 9); !errors.Is(err, domain.ErrRevisionNotFound) {
+		t.Errorf("restore of a missing revision: error = %v, want ErrRevisionNotFound", err)
+	}
+
+	restored, err := lixi.RestoreConfigRevision(ctx, created.ID, updated.Version, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Name != "Tết 2025" || restored.DrawsPerParticipant != domain.DefaultDrawsPerParticipant || restored.EndsAt != nil {
+		t.Errorf("restored config = %q, %d draws, ends %v, want revision 1's fields", restored.Name, restored.DrawsPerParticipant, restored.EndsAt)
+	}
+	if restored.Version <= updated.Version {
+		t.Errorf("restored version = %d, want more than %d", restored.Version, updated.Version)
+	}
+
+	// The restore is a new revision; history is never rewritten
+	revisions, err := lixi.GetConfigRevisions(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("%d revisions, want 3", len(revisions))
+	}
+	latest := revisions[0]
+	if latest.Revision != 3 || latest.RestoredFrom == nil || *latest.RestoredFrom != 1 {
+		t.Errorf("latest revision %d restored from %v, want revision 3 restored from 1", latest.Revision, latest.RestoredFrom)
+	}
+	if revisions[1].RestoredFrom != nil || revisions[1].Name != "Tết 2026" {
+		t.Errorf("revision 2 = %q restored from %v, want the untouched update", revisions[1].Name, revisions[1].RestoredFrom)
+	}
+
+	// Revision 3 matches revision 1 and undoes revision 2
+	diff, err := lixi.DiffConfigRevisions(ctx, created.ID, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Changes) != 0 || len(diff.Envelopes) != 0 {
+		t.Errorf("diff of revisions 1 and 3 = %+v, want none", diff)
+	}
+	diff, err = lixi.DiffConfigRevisions(ctx, created.ID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.From != 2 || diff.To != 3 || len(diff.Changes) != 3 {
+		t.Errorf("latest diff from %d to %d with changes %+v, want 2 -> 3 with name, draws and ends_at", diff.From, diff.To, diff.Changes)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"my_backend/internal/domain"
+	"my_backend/internal/logging"
+)
+
+// LixiScheduler activates configs when their scheduled window opens and
+// deactivates the active config once its window closes
+type LixiScheduler struct {
+	lixiRepo domain.LixiRepository
+	interval time.Duration
+
+	lastRun time.Time
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewLixiScheduler(lixiRepo domain.LixiRepository, interval time.Duration) *LixiScheduler {
+	return &LixiScheduler{
+		lixiRepo: lixiRepo,
+		interval: interval,
+	}
+}
+
+// Start runs Sync immediately and then every interval until Stop is called
+func (s *LixiScheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("component", "lixi_scheduler"))
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Sync(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).Error("lixi sync failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop halts the scheduler and waits for a running Sync to finish
+func (s *LixiScheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// Sync applies the schedule as of now. Windows that opened since the last run
+// take over the active slot; if nothing is active, any config currently in its
+// window is activated (covers restarts and downtime).
+func (s *LixiScheduler) Sync(ctx context.Context, now time.Time) error {
+	configs, err := s.lixiRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	var active, opened, inWindow *domain.LixiConfig
+	for _, config := range configs {
+		if config.IsActive {
+			active = config
+		}
+
+		if config.StartsAt == nil || !config.InWindow(now) {
+			continue
+		}
+		if inWindow == nil || config.StartsAt.After(*inWindow.StartsAt) {
+			inWindow = config
+		}
+		// On the first run we cannot tell what opened while we were down
+		if !s.lastRun.IsZero() && config.StartsAt.After(s.lastRun) && (opened == nil || config.StartsAt.After(*opened.StartsAt)) {
+			opened = config
+		}
+	}
+
+	if active != nil && active.EndsAt != nil && !now.Before(*active.EndsAt) {
+		if err := s.lixiRepo.Deactivate(ctx, active.ID); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("deactivated lixi config, window ended", "config_id", active.ID)
+		active = nil
+	}
+
+	next := opened
+	if next == nil && active == nil {
+		next = inWindow
+	}
+
+	if next != nil && (active == nil || active.ID != next.ID) {
+		// SetActive deactivates the previous config in the same transaction,
+		// keeping the single-active index satisfied
+		if err := s.lixiRepo.SetActive(ctx, next.ID, domain.AnyVersion); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("activated lixi config, window opened", "config_id", next.ID)
+	}
+
+	s.lastRun = now
+	return nil
+}
@@ -2,173 +2,872 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
 
 	"my_backend/internal/domain"
+	"my_backend/internal/logging"
 )
 
 type lixiService struct {
 	lixiRepo     domain.LixiRepository
 	greetingRepo domain.LixiGreetingRepository
+	auditRepo    domain.AuditRepository
+	revisionRepo domain.LixiRevisionRepository
+	transactor   domain.Transactor
+	imageStorage domain.ImageStorage
+	broadcaster  domain.GreetingBroadcaster
+	deviceSecret []byte
+	bannedWords  *wordFilter
+	random       io.Reader // Source of the draws, crypto/rand outside tests
 }
 
-func NewLixiService(lixiRepo domain.LixiRepository, greetingRepo domain.LixiGreetingRepository) domain.LixiService {
+// NewLixiService creates the lixi service. deviceSecret signs the anonymous
+// device tokens used to identify participants; greetings containing any of
+// bannedWords are refused. Config changes are recorded in auditRepo and
+// every save of a config in revisionRepo.
+func NewLixiService(lixiRepo domain.LixiRepository, greetingRepo domain.LixiGreetingRepository, auditRepo domain.AuditRepository, revisionRepo domain.LixiRevisionRepository, transactor domain.Transactor, imageStorage domain.ImageStorage, broadcaster domain.GreetingBroadcaster, deviceSecret string, bannedWords []string) domain.LixiService {
 	return &lixiService{
 		lixiRepo:     lixiRepo,
 		greetingRepo: greetingRepo,
+		auditRepo:    auditRepo,
+		revisionRepo: revisionRepo,
+		transactor:   transactor,
+		imageStorage: imageStorage,
+		broadcaster:  broadcaster,
+		deviceSecret: []byte(deviceSecret),
+		bannedWords:  newWordFilter(bannedWords),
+		random:       rand.Reader,
 	}
 }
 
-func (s *lixiService) CreateConfig(ctx context.Context, name string, envelopes []domain.LixiEnvelope) (*domain.LixiConfig, error) {
-	if name == "" {
-		return nil, errors.New("name is required")
+func (s *lixiService) CreateConfig(ctx context.Context, input domain.LixiConfigInput) (*domain.LixiConfig, error) {
+	if input.Name == "" {
+		return nil, domain.Invalid("name", "name is required")
+	}
+
+	if err := validateEnvelopes(input.Envelopes); err != nil {
+		return nil, err
+	}
+
+	var budget domain.Money
+	if input.Budget != nil {
+		budget = *input.Budget
+	}
+	budget, err := validateBudget(budget, input.Envelopes[0].Amount.Currency)
+	if err != nil {
+		return nil, err
 	}
 
+	drawsPerParticipant := domain.DefaultDrawsPerParticipant
+	if input.DrawsPerParticipant != nil {
+		if *input.DrawsPerParticipant < 1 {
+			return nil, domain.Invalid("draws_per_participant", "draws_per_participant must be at least 1")
+		}
+		drawsPerParticipant = *input.DrawsPerParticipant
+	}
+
+	config := &domain.LixiConfig{
+		Name:                input.Name,
+		Envelopes:           input.Envelopes,
+		IsActive:            false,
+		Budget:              budget,
+		DrawsPerParticipant: drawsPerParticipant,
+	}
+
+	if input.Schedule != nil {
+		if err := validateSchedule(input.Schedule); err != nil {
+			return nil, err
+		}
+		config.StartsAt, config.EndsAt = input.Schedule.StartsAt, input.Schedule.EndsAt
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.lixiRepo.Create(ctx, config); err != nil {
+			return err
+		}
+		if err := s.recordRevision(ctx, config, nil); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo, domain.AuditConfigCreate, domain.AuditTargetLixiConfig, config.ID, nil, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// validateEnvelopes checks a full set of envelopes and normalizes the
+// server-managed fields (ID by position, Drawn reset)
+func validateEnvelopes(envelopes []domain.LixiEnvelope) error {
 	if len(envelopes) != 12 {
-		return nil, errors.New("exactly 12 envelopes are required")
+		return domain.Invalid("envelopes", "exactly 12 envelopes are required")
 	}
 
-	// Validate each envelope
 	for i, env := range envelopes {
-		if env.Amount == "" {
-			return nil, errors.New("amount is required for all envelopes")
+		if env.Amount.Amount <= 0 {
+			return domain.Invalid(fmt.Sprintf("envelopes[%d].amount", i), "amount is required for all envelopes")
+		}
+		if !env.Amount.ValidCurrency() {
+			return domain.Invalid(fmt.Sprintf("envelopes[%d].amount", i), "unsupported currency "+env.Amount.Currency)
+		}
+		if env.Amount.Currency != envelopes[0].Amount.Currency {
+			return domain.Invalid("envelopes", "all envelopes must use the same currency")
 		}
 		if env.Message == "" {
-			return nil, errors.New("message is required for all envelopes")
+			return domain.Invalid(fmt.Sprintf("envelopes[%d].message", i), "message is required for all envelopes")
 		}
 		if env.Rate <= 0 {
-			return nil, errors.New("rate must be greater than 0 for all envelopes")
+			return domain.Invalid(fmt.Sprintf("envelopes[%d].rate", i), "rate must be greater than 0 for all envelopes")
+		}
+		if env.Quantity < 0 {
+			return domain.Invalid(fmt.Sprintf("envelopes[%d].quantity", i), "quantity must not be negative")
 		}
 		// Set ID based on position (1-12)
 		envelopes[i].ID = i + 1
+		envelopes[i].Drawn = 0
 	}
 
-	config := &domain.LixiConfig{
-		Name:      name,
-		Envelopes: envelopes,
-		IsActive:  false,
-	}
+	return nil
+}
 
-	if err := s.lixiRepo.Create(ctx, config); err != nil {
-		return nil, err
+// validateSchedule checks that a closed window ends after it starts
+func validateSchedule(schedule *domain.LixiSchedule) error {
+	if schedule.StartsAt != nil && schedule.EndsAt != nil && !schedule.EndsAt.After(*schedule.StartsAt) {
+		return domain.Invalid("ends_at", "ends_at must be after starts_at")
 	}
+	return nil
+}
 
-	return config, nil
+// validateBudget checks the budget and gives it the envelopes' currency when
+// none was set (e.g. zero/unlimited budgets)
+func validateBudget(budget domain.Money, currency string) (domain.Money, error) {
+	if budget.Amount < 0 {
+		return budget, domain.Invalid("budget", "budget must not be negative")
+	}
+	if budget.Currency == "" || budget.IsZero() {
+		budget.Currency = currency
+	}
+	if budget.Currency != currency {
+		return budget, domain.Invalid("budget", "budget currency must match the envelope currency")
+	}
+	return budget, nil
 }
 
+// GetActiveConfig returns the active config, treating it as not found outside
+// its scheduled window (the scheduler may not have flipped it yet)
 func (s *lixiService) GetActiveConfig(ctx context.Context) (*domain.LixiConfig, error) {
-	return s.lixiRepo.GetActive(ctx)
+	config, err := s.lixiRepo.GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.InWindow(time.Now()) {
+		return nil, domain.ErrNoActiveLixiConfig
+	}
+
+	return config, nil
 }
 
 func (s *lixiService) GetAllConfigs(ctx context.Context) ([]*domain.LixiConfig, error) {
 	return s.lixiRepo.GetAll(ctx)
 }
 
-func (s *lixiService) UpdateConfig(ctx context.Context, id string, name string, envelopes []domain.LixiEnvelope) (*domain.LixiConfig, error) {
+// GetConfigStats aggregates the draws of a config and the greetings sent
+// during its window, which runs from StartsAt (or creation) to EndsAt or now
+func (s *lixiService) GetConfigStats(ctx context.Context, id string) (*domain.LixiStats, error) {
 	if id == "" {
-		return nil, errors.New("id is required")
+		return nil, domain.Invalid("id", "id is required")
+	}
+
+	config, err := s.lixiRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	draws, err := s.lixiRepo.GetDrawStats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := config.CreatedAt, now
+	if config.StartsAt != nil {
+		from = *config.StartsAt
+	}
+	if config.EndsAt != nil && config.EndsAt.Before(now) {
+		to = *config.EndsAt
+	}
+	if to.Before(from) {
+		to = from // Not started yet
+	}
+
+	perHour, err := s.greetingRepo.CountPerHour(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	topMessages, err := s.greetingRepo.TopMessages(ctx, from, to, domain.TopMessagesLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &domain.LixiStats{
+		ConfigID:           config.ID,
+		UniqueParticipants: draws.Participants,
+		TotalPaidOut:       domain.Money{Currency: config.Budget.Currency},
+		Budget:             config.Budget,
+		GreetingsFrom:      from,
+		GreetingsTo:        to,
+		GreetingsPerHour:   perHour,
+		TopMessages:        topMessages,
+		GeneratedAt:        now,
+	}
+
+	drawn := make(map[int]domain.EnvelopeDrawCount, len(draws.Envelopes))
+	for _, count := range draws.Envelopes {
+		drawn[count.EnvelopeID] = count
+		stats.TotalDraws += count.Draws
+		stats.TotalPaidOut.Amount += count.PaidOut
+	}
+
+	var totalRate float64
+	for _, env := range config.Envelopes {
+		totalRate += env.Rate
 	}
 
+	stats.Envelopes = make([]domain.EnvelopeStats, 0, len(config.Envelopes))
+	for _, env := range config.Envelopes {
+		count := drawn[env.ID]
+		envStats := domain.EnvelopeStats{
+			EnvelopeID: env.ID,
+			Amount:     env.Amount,
+			Message:    env.Message,
+			Draws:      count.Draws,
+			PaidOut:    domain.Money{Amount: count.PaidOut, Currency: config.Budget.Currency},
+		}
+		if env.Quantity > 0 {
+			remaining := max(env.Quantity-env.Drawn, 0)
+			envStats.Remaining = &remaining
+		}
+		if totalRate > 0 {
+			envStats.ConfiguredRate = env.Rate / totalRate
+		}
+		if stats.TotalDraws > 0 {
+			envStats.ObservedRate = float64(count.Draws) / float64(stats.TotalDraws)
+		}
+		stats.Envelopes = append(stats.Envelopes, envStats)
+	}
+
+	// Return empty arrays instead of null
+	if stats.GreetingsPerHour == nil {
+		stats.GreetingsPerHour = []domain.HourlyCount{}
+	}
+	if stats.TopMessages == nil {
+		stats.TopMessages = []domain.MessageCount{}
+	}
+
+	return stats, nil
+}
+
+func (s *lixiService) GetConfig(ctx context.Context, id string) (*domain.LixiConfig, error) {
+	if id == "" {
+		return nil, domain.Invalid("id", "id is required")
+	}
+
+	return s.lixiRepo.GetByID(ctx, id)
+}
+
+// checkVersion fails fast when the client's copy of config is stale; the
+// repository checks the version again when writing
+func checkVersion(config *domain.LixiConfig, version int) error {
+	if version != domain.AnyVersion && config.Version != version {
+		return domain.ErrLixiConfigModified
+	}
+	return nil
+}
+
+func (s *lixiService) UpdateConfig(ctx context.Context, id string, version int, input domain.LixiConfigInput) (*domain.LixiConfig, error) {
+	if id == "" {
+		return nil, domain.Invalid("id", "id is required")
+	}
+
+	var config *domain.LixiConfig
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		config, err = s.updateConfig(ctx, id, version, input, domain.AuditConfigUpdate, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// updateConfig merges input into the stored config, then saves a revision
+// (restoredFrom is set by restores) and records action in the audit log; run
+// it within a transaction
+func (s *lixiService) updateConfig(ctx context.Context, id string, version int, input domain.LixiConfigInput, action string, restoredFrom *int) (*domain.LixiConfig, error) {
 	// Get existing config
 	config, err := s.lixiRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(config, version); err != nil {
+		return nil, err
+	}
+	before := snapshotConfig(config)
 
 	// Update fields if provided
-	if name != "" {
-		config.Name = name
+	if input.Name != "" {
+		config.Name = input.Name
 	}
 
-	if len(envelopes) > 0 {
-		if len(envelopes) != 12 {
-			return nil, errors.New("exactly 12 envelopes are required")
+	if len(input.Envelopes) > 0 {
+		if err := validateEnvelopes(input.Envelopes); err != nil {
+			return nil, err
 		}
+		config.Envelopes = input.Envelopes
+	}
 
-		// Validate each envelope
-		for i, env := range envelopes {
-			if env.Amount == "" {
-				return nil, errors.New("amount is required for all envelopes")
-			}
-			if env.Message == "" {
-				return nil, errors.New("message is required for all envelopes")
-			}
-			if env.Rate <= 0 {
-				return nil, errors.New("rate must be greater than 0 for all envelopes")
-			}
-			// Set ID based on position (1-12)
-			envelopes[i].ID = i + 1
+	if input.DrawsPerParticipant != nil {
+		if *input.DrawsPerParticipant < 1 {
+			return nil, domain.Invalid("draws_per_participant", "draws_per_participant must be at least 1")
 		}
-		config.Envelopes = envelopes
+		config.DrawsPerParticipant = *input.DrawsPerParticipant
+	}
+
+	if input.Schedule != nil {
+		if err := validateSchedule(input.Schedule); err != nil {
+			return nil, err
+		}
+		config.StartsAt, config.EndsAt = input.Schedule.StartsAt, input.Schedule.EndsAt
+	}
+
+	if input.Budget != nil {
+		config.Budget = *input.Budget
+	}
+	config.Budget, err = validateBudget(config.Budget, config.Envelopes[0].Amount.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Spent.IsZero() && config.Spent.Currency != config.Budget.Currency {
+		return nil, domain.Conflict("cannot change the currency of a config that has paid out")
 	}
 
 	if err := s.lixiRepo.Update(ctx, config); err != nil {
 		return nil, err
 	}
 
+	if err := s.recordRevision(ctx, config, restoredFrom); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, s.auditRepo, action, domain.AuditTargetLixiConfig, config.ID, before, config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
-func (s *lixiService) DeleteConfig(ctx context.Context, id string) error {
+// snapshotConfig copies config for the audit log before it is modified
+func snapshotConfig(config *domain.LixiConfig) *domain.LixiConfig {
+	c := *config
+	c.Envelopes = slices.Clone(config.Envelopes)
+	return &c
+}
+
+func (s *lixiService) DeleteConfig(ctx context.Context, id string, version int) error {
 	if id == "" {
-		return errors.New("id is required")
+		return domain.Invalid("id", "id is required")
 	}
 
-	// Check if config exists and is not active
-	config, err := s.lixiRepo.GetByID(ctx, id)
-	if err != nil {
-		return err
-	}
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// Check if config exists and is not active
+		config, err := s.lixiRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(config, version); err != nil {
+			return err
+		}
 
-	if config.IsActive {
-		return errors.New("cannot delete active config")
-	}
+		if config.IsActive {
+			return domain.Conflict("cannot delete active config")
+		}
 
-	return s.lixiRepo.Delete(ctx, id)
+		if err := s.lixiRepo.Delete(ctx, id, config.Version); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo, domain.AuditConfigDelete, domain.AuditTargetLixiConfig, id, config, nil)
+	})
 }
 
-func (s *lixiService) SetActiveConfig(ctx context.Context, id string) error {
+// SetActiveConfig returns the activated config with its new version
+func (s *lixiService) SetActiveConfig(ctx context.Context, id string, version int) (*domain.LixiConfig, error) {
 	if id == "" {
-		return errors.New("id is required")
+		return nil, domain.Invalid("id", "id is required")
 	}
 
-	// Verify config exists
-	_, err := s.lixiRepo.GetByID(ctx, id)
-	if err != nil {
+	var activated *domain.LixiConfig
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// Verify config exists
+		config, err := s.lixiRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(config, version); err != nil {
+			return err
+		}
+
+		previous, err := s.lixiRepo.GetActive(ctx)
+		if err != nil && !errors.Is(err, domain.ErrNoActiveLixiConfig) {
+			return err
+		}
+
+		if err := s.lixiRepo.SetActive(ctx, id, config.Version); err != nil {
+			return err
+		}
+
+		// Activating a config deactivates the one that was active
+		if previous != nil && previous.ID != id {
+			err := recordAudit(ctx, s.auditRepo, domain.AuditConfigDeactivate, domain.AuditTargetLixiConfig, previous.ID,
+				activeState{true}, activeState{false})
+			if err != nil {
+				return err
+			}
+		}
+		err = recordAudit(ctx, s.auditRepo, domain.AuditConfigActivate, domain.AuditTargetLixiConfig, id,
+			activeState{config.IsActive}, activeState{true})
+		if err != nil {
+			return err
+		}
+
+		activated, err = s.lixiRepo.GetByID(ctx, id)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.lixiRepo.SetActive(ctx, id)
+	return activated, nil
 }
 
-func (s *lixiService) SubmitGreeting(ctx context.Context, name, amount, message, image string) (*domain.LixiGreeting, error) {
+// activeState is the audit snapshot of an activation
+type activeState struct {
+	IsActive bool `json:"is_active"`
+}
+
+func (s *lixiService) SubmitGreeting(ctx context.Context, name string, amount domain.Money, message string, image domain.ImageUpload) (*domain.LixiGreeting, error) {
 	if name == "" {
-		return nil, errors.New("name is required")
+		return nil, domain.Invalid("name", "name is required")
+	}
+	if amount.Amount <= 0 {
+		return nil, domain.Invalid("amount", "amount is required")
 	}
-	if amount == "" {
-		return nil, errors.New("amount is required")
+	if !amount.ValidCurrency() {
+		return nil, domain.Invalid("amount", "unsupported currency "+amount.Currency)
 	}
 	if message == "" {
-		return nil, errors.New("message is required")
+		return nil, domain.Invalid("message", "message is required")
+	}
+	if s.bannedWords.Contains(name) {
+		return nil, domain.Invalid("name", "name contains inappropriate language")
+	}
+	if s.bannedWords.Contains(message) {
+		return nil, domain.Invalid("message", "message contains inappropriate language")
 	}
-	if image == "" {
-		return nil, errors.New("image is required")
+
+	ext, err := validateImage(image)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := newImageKey("greetings", ext)
+	if err != nil {
+		return nil, err
+	}
+
+	imageURL, err := s.imageStorage.Save(ctx, key, imageTypes[ext], image.Data)
+	if err != nil {
+		return nil, err
 	}
 
 	greeting := &domain.LixiGreeting{
 		Name:    name,
 		Amount:  amount,
 		Message: message,
-		Image:   image,
+		Image:   imageURL,
+		Status:  domain.GreetingPending, // Hidden from the public until approved
 	}
 
 	if err := s.greetingRepo.Create(ctx, greeting); err != nil {
+		// Best effort: don't leave an orphaned object behind
+		if delErr := s.imageStorage.Delete(ctx, key); delErr != nil {
+			logging.FromContext(ctx).Warn("failed to delete orphaned greeting image", "key", key, "error", delErr)
+		}
 		return nil, err
 	}
 
 	return greeting, nil
 }
 
-func (s *lixiService) GetAllGreetings(ctx context.Context) ([]*domain.LixiGreeting, error) {
-	return s.greetingRepo.GetAll(ctx)
+// imageTypes maps accepted image extensions to their MIME type
+var imageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// validateImage checks the size and sniffed MIME type of an upload (the
+// client-declared type is not trusted) and returns the file extension to use
+func validateImage(image domain.ImageUpload) (string, error) {
+	if len(image.Data) == 0 {
+		return "", domain.Invalid("image", "image is required")
+	}
+	if len(image.Data) > domain.MaxImageSize {
+		return "", domain.Invalid("image", fmt.Sprintf("image must be at most %d MB", domain.MaxImageSize>>20))
+	}
+
+	detected := http.DetectContentType(image.Data)
+	for ext, contentType := range imageTypes {
+		if detected == contentType {
+			return ext, nil
+		}
+	}
+
+	return "", domain.Invalid("image", "image must be a JPEG, PNG, GIF or WebP file")
+}
+
+// newImageKey returns a unique, unguessable object key such as
+// "greetings/2025/01/29/3f9c...e1.png"
+func newImageKey(prefix, ext string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.New("failed to generate image key")
+	}
+
+	return prefix + "/" + time.Now().UTC().Format("2006/01/02") + "/" + hex.EncodeToString(id) + ext, nil
+}
+
+func (s *lixiService) GetAllGreetings(ctx context.Context, filter domain.GreetingFilter) (*domain.GreetingPage, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = domain.DefaultGreetingPageSize
+	case filter.Limit < 0 || filter.Limit > domain.MaxGreetingPageSize:
+		return nil, domain.Invalid("limit", fmt.Sprintf("limit must be between 1 and %d", domain.MaxGreetingPageSize))
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = domain.GreetingSortCreatedAt
+	case domain.GreetingSortCreatedAt, domain.GreetingSortAmount:
+	default:
+		return nil, domain.Invalid("sort", "sort must be created_at or amount")
+	}
+
+	if filter.MinAmount != nil || filter.MaxAmount != nil {
+		if filter.Currency == "" {
+			filter.Currency = domain.CurrencyVND
+		}
+		if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+			return nil, domain.Invalid("min_amount", "min_amount must not exceed max_amount")
+		}
+	}
+	if filter.Currency != "" && !(domain.Money{Currency: filter.Currency}).ValidCurrency() {
+		return nil, domain.Invalid("currency", "unsupported currency "+filter.Currency)
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, domain.Invalid("from", "from must be before to")
+	}
+
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, domain.Invalid("status", "status must be pending, approved, rejected or hidden")
+	}
+
+	return s.greetingRepo.GetAll(ctx, filter)
+}
+
+func (s *lixiService) ModerateGreetings(ctx context.Context, ids []string, status domain.GreetingStatus, moderator string) (*domain.ModerationResult, error) {
+	// Greetings only return to pending by being resubmitted
+	if status == domain.GreetingPending || !status.Valid() {
+		return nil, domain.Invalid("status", "status must be approved, rejected or hidden")
+	}
+	if len(ids) == 0 {
+		return nil, domain.Invalid("ids", "ids are required")
+	}
+	if len(ids) > domain.MaxModerationBatch {
+		return nil, domain.Invalid("ids", fmt.Sprintf("at most %d greetings can be moderated at once", domain.MaxModerationBatch))
+	}
+
+	updated, err := s.greetingRepo.SetStatus(ctx, ids, status, moderator)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.ModerationResult{Updated: []string{}, NotFound: []string{}}
+	found := make(map[string]bool, len(updated))
+	for _, greeting := range updated {
+		result.Updated = append(result.Updated, greeting.ID)
+		found[greeting.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			result.NotFound = append(result.NotFound, id)
+			found[id] = true // Report duplicates once
+		}
+	}
+
+	logging.FromContext(ctx).Info("moderated lixi greetings", "status", status, "updated", len(updated), "not_found", len(result.NotFound))
+
+	// Update live greeting walls; they can always reload, so a failure here
+	// does not fail the moderation
+	eventType := domain.GreetingEventRemoved
+	if status == domain.GreetingApproved {
+		eventType = domain.GreetingEventAdded
+	}
+	for _, greeting := range updated {
+		if err := s.broadcaster.Publish(ctx, domain.GreetingEvent{Type: eventType, Greeting: greeting}); err != nil {
+			logging.FromContext(ctx).Warn("failed to publish greeting event", "greeting_id", greeting.ID, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *lixiService) GetPublicGreetings(ctx context.Context, cursor string, limit int) (*domain.GreetingPage, error) {
+	return s.GetAllGreetings(ctx, domain.GreetingFilter{
+		Status: domain.GreetingApproved,
+		SortBy: domain.GreetingSortCreatedAt,
+		Cursor: cursor,
+		Limit:  limit,
+	})
+}
+
+func (s *lixiService) SubscribeGreetings() (<-chan domain.GreetingEvent, func(), error) {
+	return s.broadcaster.Subscribe()
+}
+
+// maxDrawAttempts bounds how often Draw re-picks when the chosen envelope is
+// claimed by a concurrent draw between the read and the atomic update
+const maxDrawAttempts = 5
+
+func (s *lixiService) Draw(ctx context.Context, participant domain.Participant) (*domain.LixiDraw, error) {
+	participantKey, err := s.participantKey(participant)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < maxDrawAttempts; attempt++ {
+		config, err := s.GetActiveConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Answer repeat participants with their previous result up front;
+		// RecordDraw still enforces the limit atomically
+		if attempt == 0 {
+			previous, err := s.lixiRepo.GetParticipantDraws(ctx, config.ID, participantKey)
+			if err != nil {
+				return nil, err
+			}
+			if len(previous) >= config.DrawsPerParticipant {
+				return nil, &domain.DrawLimitError{Previous: previous}
+			}
+		}
+
+		envelope, err := pickEnvelope(s.random, drawableEnvelopes(config))
+		if err != nil {
+			return nil, err
+		}
+
+		draw := &domain.LixiDraw{
+			ConfigID:   config.ID,
+			EnvelopeID: envelope.ID,
+			Amount:     envelope.Amount,
+			Message:    envelope.Message,
+
+			ParticipantKey: participantKey,
+		}
+
+		err = s.lixiRepo.RecordDraw(ctx, draw)
+		if errors.Is(err, domain.ErrEnvelopeUnavailable) {
+			continue
+		}
+		if errors.Is(err, domain.ErrDrawLimitReached) {
+			previous, err := s.lixiRepo.GetParticipantDraws(ctx, config.ID, participantKey)
+			if err != nil {
+				return nil, err
+			}
+			return nil, &domain.DrawLimitError{Previous: previous}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		logging.FromContext(ctx).Info("lixi envelope drawn",
+			"config_id", draw.ConfigID,
+			"envelope_id", draw.EnvelopeID,
+			"amount", draw.Amount.Amount,
+			"currency", draw.Amount.Currency,
+		)
+		return draw, nil
+	}
+
+	return nil, domain.Unavailable("too many concurrent draws, please try again")
+}
+
+// drawableEnvelopes returns the envelopes that are in stock and whose payout
+// still fits in the remaining budget
+func drawableEnvelopes(config *domain.LixiConfig) []domain.LixiEnvelope {
+	var envelopes []domain.LixiEnvelope
+	for _, env := range config.Envelopes {
+		if !env.InStock() {
+			continue
+		}
+		if !config.Budget.IsZero() && config.Spent.Amount+env.Amount.Amount > config.Budget.Amount {
+			continue
+		}
+		envelopes = append(envelopes, env)
+	}
+	return envelopes
+}
+
+// drawPrecision is the number of random bits used for a draw; 53 bits is the
+// full mantissa of a float64, so every weight keeps its exact share.
+const drawPrecision = 53
+
+// pickEnvelope performs a weighted random pick over envelopes using their Rate
+// as weight. random is crypto/rand in production so the outcome cannot be
+// predicted.
+func pickEnvelope(random io.Reader, envelopes []domain.LixiEnvelope) (*domain.LixiEnvelope, error) {
+	var total float64
+	last := -1
+	for i, env := range envelopes {
+		if env.Rate > 0 {
+			total += env.Rate
+			last = i
+		}
+	}
+	if last == -1 {
+		return nil, domain.Conflict("all envelopes have been claimed")
+	}
+
+	n, err := rand.Int(random, big.NewInt(1<<drawPrecision))
+	if err != nil {
+		return nil, errors.New("failed to generate random number")
+	}
+	target := float64(n.Int64()) / (1 << drawPrecision) * total
+
+	for i, env := range envelopes {
+		if env.Rate <= 0 {
+			continue
+		}
+		target -= env.Rate
+		if target < 0 {
+			return &envelopes[i], nil
+		}
+	}
+
+	// Floating point rounding can leave target at ~0 after the last weight
+	return &envelopes[last], nil
+}
+
+// participantKey turns the participant identity into the key their draws are
+// counted under, e.g. "user:42", "phone:0901234567" or "device:<id>"
+func (s *lixiService) participantKey(participant domain.Participant) (string, error) {
+	switch {
+	case participant.UserID != "":
+		return "user:" + participant.UserID, nil
+	case participant.Phone != "":
+		phone, err := normalizePhone(participant.Phone)
+		if err != nil {
+			return "", err
+		}
+		return "phone:" + phone, nil
+	case participant.Email != "":
+		email := strings.ToLower(strings.TrimSpace(participant.Email))
+		if !strings.Contains(email, "@") {
+			return "", domain.Invalid("email", "invalid email format")
+		}
+		return "email:" + email, nil
+	case participant.DeviceToken != "":
+		deviceID, err := s.verifyDeviceToken(participant.DeviceToken)
+		if err != nil {
+			return "", err
+		}
+		return "device:" + deviceID, nil
+	default:
+		return "", domain.Invalid("participant", "participant identification required: sign in or provide a phone, email or device token")
+	}
+}
+
+// normalizePhone keeps only digits and rewrites the +84 country code to the
+// local "0" prefix so "+84 901 234 567" and "0901234567" are the same person
+func normalizePhone(phone string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+
+	if strings.HasPrefix(digits, "84") && len(digits) >= 11 {
+		digits = "0" + digits[2:]
+	}
+
+	if len(digits) < 9 || len(digits) > 15 {
+		return "", domain.Invalid("phone", "invalid phone number")
+	}
+
+	return digits, nil
+}
+
+// IssueDeviceToken returns a new anonymous participant token of the form
+// "<device id>.<HMAC-SHA256 signature>"
+func (s *lixiService) IssueDeviceToken(ctx context.Context) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.New("failed to generate device token")
+	}
+
+	deviceID := base64.RawURLEncoding.EncodeToString(id)
+	return deviceID + "." + s.signDeviceID(deviceID), nil
+}
+
+func (s *lixiService) signDeviceID(deviceID string) string {
+	mac := hmac.New(sha256.New, s.deviceSecret)
+	mac.Write([]byte("lixi-device:" + deviceID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyDeviceToken checks the signature of a token from IssueDeviceToken and
+// returns its device id
+func (s *lixiService) verifyDeviceToken(token string) (string, error) {
+	deviceID, signature, found := strings.Cut(token, ".")
+	if !found || deviceID == "" {
+		return "", domain.Invalid("device_token", "invalid device token")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.signDeviceID(deviceID))) {
+		return "", domain.Invalid("device_token", "invalid device token")
+	}
+
+	return deviceID, nil
 }
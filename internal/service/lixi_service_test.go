@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"testing/iotest"
+
+	"my_backend/internal/domain"
+)
+
+// randomAt returns a random source whose next draw lands at fraction
+// (in [0, 1)) of the total weight
+func randomAt(fraction float64) *bytes.Reader {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(fraction*(1<<drawPrecision)))
+	return bytes.NewReader(b[1:]) // rand.Int reads 7 bytes for 53 bits
+}
+
+func envelopes(rates ...float64) []domain.LixiEnvelope {
+	envs := make([]domain.LixiEnvelope, len(rates))
+	for i, rate := range rates {
+		envs[i] = domain.LixiEnvelope{ID: i + 1, Amount: domain.Money{Amount: int64(i+1) * 10000, Currency: "VND"}, Rate: rate}
+	}
+	return envs
+}
+
+func TestPickEnvelopeWeights(t *testing.T) {
+	tests := []struct {
+		name     string
+		rates    []float64
+		fraction float64
+		want     int // Envelope ID
+	}{
+		{"start of first weight", []float64{1, 3}, 0, 1},
+		{"inside first weight", []float64{1, 3}, 0.2, 1},
+		{"first weight is exclusive at its end", []float64{1, 3}, 0.25, 2},
+		{"end of last weight", []float64{1, 3}, 0.999999, 2},
+		{"zero rates are skipped at the start", []float64{0, 2, 2}, 0, 2},
+		{"zero rates are skipped in between", []float64{2, 0, 2}, 0.5, 3},
+		{"zero rates are skipped at the end", []float64{2, 2, 0}, 0.999999, 2},
+		{"negative rates are skipped", []float64{-5, 1}, 0, 2},
+		{"single drawable envelope", []float64{0, 0, 0.1, 0}, 0.7, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickEnvelope(randomAt(tt.fraction), envelopes(tt.rates...))
+			if err != nil {
+				t.Fatalf("pickEnvelope: %v", err)
+			}
+			if got.ID != tt.want {
+				t.Errorf("picked envelope %d, want %d", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickEnvelopeAllClaimed(t *testing.T) {
+	for _, envs := range [][]domain.LixiEnvelope{nil, envelopes(0, 0, 0)} {
+		_, err := pickEnvelope(randomAt(0), envs)
+		if !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("pickEnvelope(%d envelopes without weight) error = %v, want a conflict", len(envs), err)
+		}
+	}
+}
+
+func TestPickEnvelopeRandomFailure(t *testing.T) {
+	_, err := pickEnvelope(iotest.ErrReader(errors.New("no entropy")), envelopes(1))
+	if err == nil {
+		t.Error("pickEnvelope succeeded without randomness")
+	}
+}
+
+func TestDrawableEnvelopes(t *testing.T) {
+	envs := envelopes(1, 1, 1, 1)
+	envs[0].Quantity, envs[0].Drawn = 2, 2 // Out of stock
+	envs[1].Quantity, envs[1].Drawn = 2, 1 // One left
+	envs[3].Amount.Amount = 60000          // Over the remaining budget
+
+	config := &domain.LixiConfig{
+		Envelopes: envs,
+		Budget:    domain.Money{Amount: 100000, Currency: "VND"},
+		Spent:     domain.Money{Amount: 50000, Currency: "VND"},
+	}
+
+	drawable := drawableEnvelopes(config)
+	var ids []int
+	for _, env := range drawable {
+		ids = append(ids, env.ID)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Fatalf("drawable envelopes = %v, want [2 3]", ids)
+	}
+
+	// The pick starts at the first drawable envelope, not the sold out one
+	got, err := pickEnvelope(randomAt(0), drawable)
+	if err != nil || got.ID != 2 {
+		t.Errorf("pickEnvelope = %v, %v, want envelope 2", got, err)
+	}
+
+	// Everything sold out is reported as claimed
+	for i := range config.Envelopes {
+		config.Envelopes[i].Quantity, config.Envelopes[i].Drawn = 1, 1
+	}
+	if _, err := pickEnvelope(randomAt(0), drawableEnvelopes(config)); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("pickEnvelope with everything sold out error = %v, want a conflict", err)
+	}
+}
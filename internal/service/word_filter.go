@@ -0,0 +1,82 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wordFilter finds banned words or phrases in user-submitted text. Matching
+// ignores case, Vietnamese diacritics and punctuation and only hits whole
+// words, so banning "ass" does not reject "class" and banning "đồ ngốc" also
+// rejects "do ngoc".
+type wordFilter struct {
+	banned []string // Normalized, padded with spaces
+}
+
+func newWordFilter(words []string) *wordFilter {
+	f := &wordFilter{}
+	for _, word := range words {
+		if normalized := normalizeWords(word); normalized != "  " {
+			f.banned = append(f.banned, normalized)
+		}
+	}
+	return f
+}
+
+// Contains reports whether text contains any banned word
+func (f *wordFilter) Contains(text string) bool {
+	if len(f.banned) == 0 {
+		return false
+	}
+
+	normalized := normalizeWords(text)
+	for _, banned := range f.banned {
+		if strings.Contains(normalized, banned) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWords lowercases s, strips its diacritics and reduces it to its
+// words separated by single spaces, with a space on either end:
+// "Chúc, MỪNG!" -> " chuc mung "
+func normalizeWords(s string) string {
+	words := strings.FieldsFunc(foldDiacritics(strings.ToLower(s)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
+
+// diacriticBases maps each base letter to its lowercase Vietnamese variants
+var diacriticBases = map[rune]string{
+	'a': "àáảãạăằắẳẵặâầấẩẫậ",
+	'd': "đ",
+	'e': "èéẻẽẹêềếểễệ",
+	'i': "ìíỉĩị",
+	'o': "òóỏõọôồốổỗộơờớởỡợ",
+	'u': "ùúủũụưừứửữự",
+	'y': "ỳýỷỹỵ",
+}
+
+// foldedLetters maps every variant in diacriticBases to its base letter
+var foldedLetters = func() map[rune]rune {
+	folded := make(map[rune]rune)
+	for base, variants := range diacriticBases {
+		for _, r := range variants {
+			folded[r] = base
+		}
+	}
+	return folded
+}()
+
+// foldDiacritics replaces the Vietnamese letters of lowercase s with their
+// unaccented base letter ("đồng" -> "dong")
+func foldDiacritics(s string) string {
+	return strings.Map(func(r rune) rune {
+		if base, ok := foldedLetters[r]; ok {
+			return base
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,60 @@
+package service
+
+import "testing"
+
+func TestNormalizeWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Hi, THERE!", " hi there "},
+		{"  spaced\tout\n", " spaced out "},
+		{"Chúc MỪNG năm mới", " chuc mung nam moi "},
+		{"Đồng đội", " dong doi "},
+		{"ƯỚC", " uoc "},
+		{"2025!!!", " 2025 "},
+		{"", "  "},
+		{"?!", "  "},
+	}
+	for _, tt := range tests {
+		if got := normalizeWords(tt.in); got != tt.want {
+			t.Errorf("normalizeWords(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWordFilter(t *testing.T) {
+	filter := newWordFilter([]string{"ass", "đồ ngốc", "Dumb", " ", "!"})
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"ass", true},
+		{"You ASS!", true},
+		{"a class act", false},
+		{"assassin", false},
+		{"đồ ngốc", true},
+		{"Đồ Ngốc!", true},
+		{"do ngoc", true},     // Accents dropped
+		{"dồ, ngộc...", true}, // Other accents
+		{"đồ ngọt", false},    // Different word
+		{"đồngốc", false},     // Not the whole phrase
+		{"dụmb", true},        // Accented spelling of a plain banned word
+		{"Chúc mừng năm mới", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := filter.Contains(tt.text); got != tt.want {
+			t.Errorf("Contains(%q) = %t, want %t", tt.text, got, tt.want)
+		}
+	}
+
+	// Blank banned words are skipped rather than matching everything
+	if len(filter.banned) != 3 {
+		t.Errorf("banned = %q, want 3 words", filter.banned)
+	}
+	if newWordFilter(nil).Contains("anything") {
+		t.Error("an empty filter matched")
+	}
+}
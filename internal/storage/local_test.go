@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalSaveAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewLocalImageStorage(dir, "/uploads/")
+	if err != nil {
+		t.Fatalf("NewLocalImageStorage: %v", err)
+	}
+
+	url, err := storage.Save(context.Background(), "greetings/1.png", "image/png", []byte("png data"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if url != "/uploads/greetings/1.png" {
+		t.Errorf("url = %q, want /uploads/greetings/1.png", url)
+	}
+
+	path := filepath.Join(dir, "greetings", "1.png")
+	if data, err := os.ReadFile(path); err != nil || string(data) != "png data" {
+		t.Fatalf("stored file = %q, %v", data, err)
+	}
+
+	if err := storage.Delete(context.Background(), "greetings/1.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+
+	// Deleting a missing image is not an error
+	if err := storage.Delete(context.Background(), "greetings/1.png"); err != nil {
+		t.Errorf("Delete of missing image: %v", err)
+	}
+}
+
+func TestLocalRejectsKeysEscapingDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	storage, err := NewLocalImageStorage(dir, "/uploads")
+	if err != nil {
+		t.Fatalf("NewLocalImageStorage: %v", err)
+	}
+
+	outside := filepath.Join(root, "outside.png")
+	if err := os.WriteFile(outside, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"../outside.png", "greetings/../../outside.png", "/etc/passwd", outside, ""} {
+		if _, err := storage.Save(context.Background(), key, "image/png", []byte("x")); err == nil {
+			t.Errorf("Save(%q) succeeded, want an invalid key error", key)
+		}
+		if err := storage.Delete(context.Background(), key); err == nil {
+			t.Errorf("Delete(%q) succeeded, want an invalid key error", key)
+		}
+	}
+
+	if data, err := os.ReadFile(outside); err != nil || string(data) != "keep" {
+		t.Errorf("file outside the upload directory changed: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// s3Request is what the stand-in server saw of one request
+type s3Request struct {
+	method, path, contentType, auth, contentSHA, amzDate string
+	body                                                 string
+}
+
+// newS3StandIn starts a server answering every request with status and
+// recording what it received
+func newS3StandIn(t *testing.T, status int) (*httptest.Server, func() []s3Request) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []s3Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, s3Request{
+			method:      r.Method,
+			path:        r.URL.EscapedPath(),
+			contentType: r.Header.Get("Content-Type"),
+			auth:        r.Header.Get("Authorization"),
+			contentSHA:  r.Header.Get("X-Amz-Content-Sha256"),
+			amzDate:     r.Header.Get("X-Amz-Date"),
+			body:        string(body),
+		})
+		mu.Unlock()
+
+		w.WriteHeader(status)
+		if status >= 300 {
+			io.WriteString(w, "<Error><Code>AccessDenied</Code></Error>")
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []s3Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]s3Request(nil), requests...)
+	}
+}
+
+func newTestS3Storage(t *testing.T, endpoint, publicURL string) *s3ImageStorage {
+	t.Helper()
+
+	storage, err := NewS3ImageStorage(S3Config{
+		Endpoint:        endpoint,
+		Region:          "ap-southeast-1",
+		Bucket:          "lixi",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		PublicURL:       publicURL,
+	})
+	if err != nil {
+		t.Fatalf("NewS3ImageStorage: %v", err)
+	}
+	return storage.(*s3ImageStorage)
+}
+
+var authorizationPattern = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/(\d{8})/ap-southeast-1/s3/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=[0-9a-f]{64}$`)
+
+func TestS3SaveSignsPathStylePut(t *testing.T) {
+	server, requests := newS3StandIn(t, http.StatusOK)
+	storage := newTestS3Storage(t, server.URL+"/", "")
+
+	url, err := storage.Save(context.Background(), "greetings/a b.png", "image/png", []byte("png data"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if want := server.URL + "/lixi/greetings/a%20b.png"; url != want {
+		t.Errorf("url = %q, want %q", url, want)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("got %d requests, want 1", len(got))
+	}
+	req := got[0]
+
+	if req.method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", req.method)
+	}
+	if req.path != "/lixi/greetings/a%20b.png" {
+		t.Errorf("path = %q, want path-style /lixi/greetings/a%%20b.png", req.path)
+	}
+	if req.body != "png data" || req.contentType != "image/png" {
+		t.Errorf("body/content type = %q/%q", req.body, req.contentType)
+	}
+	if want := sha256Hex([]byte("png data")); req.contentSHA != want {
+		t.Errorf("x-amz-content-sha256 = %q, want %q", req.contentSHA, want)
+	}
+
+	m := authorizationPattern.FindStringSubmatch(req.auth)
+	if m == nil {
+		t.Fatalf("Authorization = %q, not a SigV4 header for the configured key and region", req.auth)
+	}
+	if !strings.HasPrefix(req.amzDate, m[1]+"T") {
+		t.Errorf("credential date %s does not match x-amz-date %s", m[1], req.amzDate)
+	}
+	if m[2] != "content-type;host;x-amz-content-sha256;x-amz-date" {
+		t.Errorf("SignedHeaders = %q", m[2])
+	}
+}
+
+func TestS3DeleteSignsEmptyPayload(t *testing.T) {
+	server, requests := newS3StandIn(t, http.StatusNoContent)
+	storage := newTestS3Storage(t, server.URL, "")
+
+	if err := storage.Delete(context.Background(), "greetings/1.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("got %d requests, want 1", len(got))
+	}
+	req := got[0]
+
+	if req.method != http.MethodDelete || req.path != "/lixi/greetings/1.png" {
+		t.Errorf("request = %s %s, want DELETE /lixi/greetings/1.png", req.method, req.path)
+	}
+	if want := sha256Hex(nil); req.contentSHA != want {
+		t.Errorf("x-amz-content-sha256 = %q, want hash of empty payload %q", req.contentSHA, want)
+	}
+	m := authorizationPattern.FindStringSubmatch(req.auth)
+	if m == nil || m[2] != "host;x-amz-content-sha256;x-amz-date" {
+		t.Errorf("Authorization = %q, want SigV4 without content-type", req.auth)
+	}
+}
+
+func TestS3ErrorStatus(t *testing.T) {
+	server, _ := newS3StandIn(t, http.StatusForbidden)
+	storage := newTestS3Storage(t, server.URL, "")
+
+	_, err := storage.Save(context.Background(), "greetings/1.png", "image/png", []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Save error = %v, want the 403 status and response body", err)
+	}
+
+	err = storage.Delete(context.Background(), "greetings/1.png")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Delete error = %v, want the 403 status", err)
+	}
+}
+
+func TestS3PublicURL(t *testing.T) {
+	server, _ := newS3StandIn(t, http.StatusOK)
+	storage := newTestS3Storage(t, server.URL, "https://cdn.example.com/images/")
+
+	url, err := storage.Save(context.Background(), "greetings/ảnh.png", "image/png", []byte("x"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if want := "https://cdn.example.com/images/greetings/%E1%BA%A3nh.png"; url != want {
+		t.Errorf("url = %q, want %q", url, want)
+	}
+}
+
+func TestS3SignatureDependsOnSecret(t *testing.T) {
+	server, requests := newS3StandIn(t, http.StatusOK)
+
+	for _, secret := range []string{"secret", "other"} {
+		storage := newTestS3Storage(t, server.URL, "")
+		storage.config.SecretAccessKey = secret
+		if _, err := storage.Save(context.Background(), "k.png", "image/png", []byte("x")); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	got := requests()
+	sig := func(auth string) string { return auth[strings.LastIndex(auth, "=")+1:] }
+	if got[0].amzDate == got[1].amzDate && sig(got[0].auth) == sig(got[1].auth) {
+		t.Error("signatures with different secrets are equal")
+	}
+}
+
+func TestNewS3ImageStorageRequiresConfig(t *testing.T) {
+	if _, err := NewS3ImageStorage(S3Config{Endpoint: "http://localhost:9000", Bucket: "lixi"}); err == nil {
+		t.Error("NewS3ImageStorage without credentials succeeded")
+	}
+}